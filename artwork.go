@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/codegoalie/stream-player/artwork"
+	"github.com/codegoalie/stream-player/models"
+)
+
+const (
+	artworkTimeout = 10 * time.Second
+	artworkColumns = 16
+	artworkRows    = 8
+)
+
+// artworkDisplay downloads track artwork in the background and draws it to
+// out once it arrives. out is outside the redrawn status frame, since
+// inline images can't be cleared and redrawn like lines of text.
+type artworkDisplay struct {
+	cache    *artwork.Cache
+	protocol artwork.Protocol
+	out      io.Writer
+
+	mu sync.Mutex
+	// url is the artwork of the track being shown. Downloads of any other
+	// finish too late and are dropped.
+	url  string
+	path string
+	// lastID is the ID given to the latest download and drawnID that of
+	// the image on screen, which is deleted when replaced
+	lastID  uint32
+	drawnID uint32
+}
+
+// show starts downloading info's artwork, drawing it once it arrives
+func (a *artworkDisplay) show(info *models.TrackInfo) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if info.ArtURL == a.url {
+		return
+	}
+	a.url = info.ArtURL
+	a.path = ""
+	if a.url != "" {
+		a.lastID++
+		go a.fetch(a.url, a.lastID)
+	}
+}
+
+// fetch caches and renders the artwork at url, drawing it if it's still
+// wanted
+func (a *artworkDisplay) fetch(url string, id uint32) {
+	ctx, cancel := context.WithTimeout(context.Background(), artworkTimeout)
+	defer cancel()
+
+	path, err := a.cache.Fetch(ctx, url)
+	if err != nil {
+		return
+	}
+	var rendered bytes.Buffer
+	if err := artwork.Render(&rendered, path, a.protocol, artworkColumns, artworkRows, id); err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if url != a.url {
+		return
+	}
+	a.path = path
+	artwork.Delete(a.out, a.protocol, a.drawnID)
+	a.out.Write(rendered.Bytes())
+	a.drawnID = id
+}
+
+// current is where the shown artwork is cached, empty until it has
+// downloaded
+func (a *artworkDisplay) current() string {
+	if a == nil {
+		return ""
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.path
+}
@@ -0,0 +1,160 @@
+package artwork
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codegoalie/stream-player/utils"
+)
+
+const (
+	// DefaultMaxFileBytes is the largest image which will be downloaded
+	DefaultMaxFileBytes = 5 << 20
+	// DefaultMaxCacheBytes is the total size of cached images before the least
+	// recently used are removed
+	DefaultMaxCacheBytes = 50 << 20
+)
+
+// ErrTooLarge is returned when artwork exceeds the cache's MaxFileBytes
+var ErrTooLarge = errors.New("artwork is too large")
+
+// Cache downloads artwork into a directory and limits its size
+type Cache struct {
+	Dir           string
+	MaxFileBytes  int64
+	MaxCacheBytes int64
+}
+
+// NewCache builds a Cache with default limits in the user's cache directory
+func NewCache() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		err = fmt.Errorf("failed to find user cache dir: %w", err)
+		return nil, err
+	}
+
+	return &Cache{
+		Dir:           filepath.Join(dir, "stream-player", "artwork"),
+		MaxFileBytes:  DefaultMaxFileBytes,
+		MaxCacheBytes: DefaultMaxCacheBytes,
+	}, nil
+}
+
+// Fetch returns the path of the cached copy of the artwork at url,
+// downloading it first if needed
+func (c *Cache) Fetch(ctx context.Context, url string) (string, error) {
+	sum := sha1.Sum([]byte(url))
+	ext := strings.ToLower(path.Ext(strings.SplitN(url, "?", 2)[0]))
+	if len(ext) > 5 {
+		ext = ""
+	}
+	dst := filepath.Join(c.Dir, hex.EncodeToString(sum[:])+ext)
+
+	if _, err := os.Stat(dst); err == nil {
+		// mark as recently used so pruning keeps it
+		now := time.Now()
+		_ = os.Chtimes(dst, now, now)
+		return dst, nil
+	}
+
+	err := c.download(ctx, url, dst)
+	if err != nil {
+		return "", err
+	}
+
+	c.prune(dst)
+	return dst, nil
+}
+
+func (c *Cache) download(ctx context.Context, url, dst string) error {
+	resp, err := utils.DefaultClient.Open(ctx, url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch artwork: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if c.MaxFileBytes > 0 && resp.ContentLength > c.MaxFileBytes {
+		return ErrTooLarge
+	}
+
+	err = os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create artwork cache dir: %w", err)
+	}
+
+	tmp, err := ioutil.TempFile(c.Dir, ".download-")
+	if err != nil {
+		return fmt.Errorf("failed to create artwork file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	var body io.Reader = resp.Body
+	if c.MaxFileBytes > 0 {
+		body = io.LimitReader(resp.Body, c.MaxFileBytes+1)
+	}
+	n, err := io.Copy(tmp, body)
+	closeErr := tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to download artwork: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write artwork: %w", closeErr)
+	}
+	if c.MaxFileBytes > 0 && n > c.MaxFileBytes {
+		return ErrTooLarge
+	}
+
+	err = os.Rename(tmp.Name(), dst)
+	if err != nil {
+		return fmt.Errorf("failed to save artwork: %w", err)
+	}
+
+	return nil
+}
+
+// prune removes the least recently used files until the cache fits in
+// MaxCacheBytes, always keeping keep
+func (c *Cache) prune(keep string) {
+	if c.MaxCacheBytes <= 0 {
+		return
+	}
+
+	files, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+	}
+
+	for _, f := range files {
+		if total <= c.MaxCacheBytes {
+			return
+		}
+
+		name := filepath.Join(c.Dir, f.Name())
+		if f.IsDir() || name == keep {
+			continue
+		}
+		if os.Remove(name) == nil {
+			total -= f.Size()
+		}
+	}
+}
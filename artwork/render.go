@@ -0,0 +1,247 @@
+package artwork
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	// register decoders for the formats stations serve
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// Protocol is a way of drawing images in a terminal
+type Protocol string
+
+// Supported protocols
+const (
+	Auto   Protocol = "auto"
+	Kitty  Protocol = "kitty"
+	ITerm  Protocol = "iterm"
+	Sixel  Protocol = "sixel"
+	Blocks Protocol = "blocks"
+	None   Protocol = "none"
+)
+
+// approximate pixel size of a terminal cell used when the protocol needs
+// pixels instead of cells
+const (
+	cellWidth  = 8
+	cellHeight = 16
+)
+
+// ParseProtocol converts a flag value into a Protocol
+func ParseProtocol(s string) (Protocol, error) {
+	switch p := Protocol(strings.ToLower(s)); p {
+	case Auto, Kitty, ITerm, Sixel, Blocks, None:
+		return p, nil
+	}
+
+	return None, fmt.Errorf("unknown artwork protocol %q", s)
+}
+
+// DetectProtocol guesses the best protocol supported by the terminal on
+// stdout from its environment
+func DetectProtocol() Protocol {
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return None
+	}
+
+	term := os.Getenv("TERM")
+	program := os.Getenv("TERM_PROGRAM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "" || term == "xterm-kitty":
+		return Kitty
+	case program == "iTerm.app" || program == "WezTerm":
+		return ITerm
+	case strings.Contains(term, "sixel") || program == "mlterm" || term == "foot":
+		return Sixel
+	case term == "" || term == "dumb":
+		return None
+	}
+
+	return Blocks
+}
+
+// Render draws the image at path to w in about cols by rows terminal cells.
+// The image is drawn at the cursor, which is left on the line below it, so
+// it's meant to be written once rather than as part of a frame which is
+// redrawn. Terminals which keep images, like kitty, are given id for it so
+// it can be removed with Delete, or pick one themselves when id is 0.
+func Render(w io.Writer, path string, protocol Protocol, cols, rows int, id uint32) error {
+	if protocol == Auto {
+		protocol = DetectProtocol()
+	}
+	if protocol == None {
+		return nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read artwork: %w", err)
+	}
+
+	if protocol == ITerm {
+		return renderITerm(w, raw, cols, rows)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("failed to decode artwork: %w", err)
+	}
+
+	switch protocol {
+	case Kitty:
+		return renderKitty(w, img, cols, rows, id)
+	case Sixel:
+		return renderSixel(w, resize(img, cols*cellWidth, rows*cellHeight))
+	default:
+		return renderBlocks(w, resize(img, cols, rows*2))
+	}
+}
+
+func renderITerm(w io.Writer, raw []byte, cols, rows int) error {
+	_, err := fmt.Fprintf(
+		w,
+		"\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1:%s\a\n",
+		len(raw),
+		cols,
+		rows,
+		base64.StdEncoding.EncodeToString(raw),
+	)
+	return err
+}
+
+// Delete removes the image drawn by Render with id from terminals which keep
+// images, freeing its data. Other terminals need nothing removed.
+func Delete(w io.Writer, protocol Protocol, id uint32) error {
+	if protocol == Auto {
+		protocol = DetectProtocol()
+	}
+	if protocol != Kitty || id == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", id)
+	return err
+}
+
+// renderKitty sends the image as PNG in chunks as the kitty graphics protocol
+// requires
+func renderKitty(w io.Writer, img image.Image, cols, rows int, id uint32) error {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return fmt.Errorf("failed to encode artwork: %w", err)
+	}
+
+	const chunkSize = 4096
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	first := true
+	for len(data) > 0 {
+		n := chunkSize
+		if n > len(data) {
+			n = len(data)
+		}
+		more := 0
+		if n < len(data) {
+			more = 1
+		}
+
+		if first {
+			key := ""
+			if id != 0 {
+				key = fmt.Sprintf("i=%d,", id)
+			}
+			_, err = fmt.Fprintf(w, "\x1b_Gf=100,a=T,%sq=2,c=%d,r=%d,m=%d;%s\x1b\\", key, cols, rows, more, data[:n])
+			first = false
+		} else {
+			_, err = fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, data[:n])
+		}
+		if err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+
+	_, err = fmt.Fprintln(w)
+	return err
+}
+
+// renderBlocks draws two pixels per cell using the upper half block with
+// 24-bit foreground and background colors
+func renderBlocks(w io.Writer, img *image.RGBA) error {
+	var b strings.Builder
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 2 {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			top := img.RGBAAt(x, y)
+			bottom := color.RGBA{}
+			if y+1 < bounds.Max.Y {
+				bottom = img.RGBAAt(x, y+1)
+			}
+			fmt.Fprintf(
+				&b,
+				"\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀",
+				top.R, top.G, top.B,
+				bottom.R, bottom.G, bottom.B,
+			)
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// resize scales img to width by height averaging the source pixels covered
+// by each destination pixel and flattening transparency onto black
+func resize(img image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	src := img.Bounds()
+	if src.Empty() || width <= 0 || height <= 0 {
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := src.Min.Y + y*src.Dy()/height
+		y1 := src.Min.Y + (y+1)*src.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := src.Min.X + x*src.Dx()/width
+			x1 := src.Min.X + (x+1)*src.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// premultiplied alpha flattens onto black
+					cr, cg, cb, _ := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,70 @@
+package artwork
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artwork")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "art.png")
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, img.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		protocol Protocol
+		contains string
+		lines    int
+	}{
+		{Blocks, "▀", 4},
+		{Kitty, "\x1b_Gf=100,a=T,i=7,q=2,c=8,r=4,", 1},
+		{ITerm, "\x1b]1337;File=inline=1;", 1},
+		{Sixel, "\x1bPq", 1},
+		{None, "", 0},
+	} {
+		var out bytes.Buffer
+		if err := Render(&out, path, test.protocol, 8, 4, 7); err != nil {
+			t.Errorf("%s: %v", test.protocol, err)
+			continue
+		}
+		if !strings.Contains(out.String(), test.contains) {
+			t.Errorf("%s: %q doesn't contain %q", test.protocol, out.String(), test.contains)
+		}
+		if lines := strings.Count(out.String(), "\n"); lines != test.lines {
+			t.Errorf("%s: drew %d lines, want %d", test.protocol, lines, test.lines)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	var out bytes.Buffer
+	if err := Delete(&out, Kitty, 7); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "\x1b_Ga=d,d=I,i=7,q=2\x1b\\" {
+		t.Errorf("got %q", got)
+	}
+
+	for _, protocol := range []Protocol{Blocks, ITerm, Sixel, None} {
+		out.Reset()
+		Delete(&out, protocol, 7)
+		if out.Len() != 0 {
+			t.Errorf("%s: deleted with %q", protocol, out.String())
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package artwork
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+)
+
+// sixel colors are a 6x6x6 cube so no palette needs to be computed per image
+const sixelLevels = 6
+
+func sixelIndex(r, g, b uint8) int {
+	level := func(v uint8) int { return int(v) * (sixelLevels - 1) / 255 }
+	return level(r)*sixelLevels*sixelLevels + level(g)*sixelLevels + level(b)
+}
+
+// renderSixel encodes img as a DEC sixel graphic
+func renderSixel(w io.Writer, img *image.RGBA) error {
+	bw := bufio.NewWriter(w)
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	fmt.Fprintf(bw, "\x1bPq\"1;1;%d;%d", width, height)
+	for i := 0; i < sixelLevels*sixelLevels*sixelLevels; i++ {
+		r := i / (sixelLevels * sixelLevels)
+		g := i / sixelLevels % sixelLevels
+		b := i % sixelLevels
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", i, r*100/(sixelLevels-1), g*100/(sixelLevels-1), b*100/(sixelLevels-1))
+	}
+
+	indexes := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			indexes[y*width+x] = sixelIndex(c.R, c.G, c.B)
+		}
+	}
+
+	row := make([]byte, width)
+	for band := 0; band < height; band += 6 {
+		used := map[int]bool{}
+		for y := band; y < band+6 && y < height; y++ {
+			for x := 0; x < width; x++ {
+				used[indexes[y*width+x]] = true
+			}
+		}
+
+		first := true
+		for c := 0; c < sixelLevels*sixelLevels*sixelLevels; c++ {
+			if !used[c] {
+				continue
+			}
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && band+dy < height; dy++ {
+					if indexes[(band+dy)*width+x] == c {
+						bits |= 1 << uint(dy)
+					}
+				}
+				row[x] = '?' + bits
+			}
+
+			if !first {
+				bw.WriteByte('$')
+			}
+			first = false
+			fmt.Fprintf(bw, "#%d", c)
+			writeSixelRun(bw, row)
+		}
+		bw.WriteByte('-')
+	}
+
+	bw.WriteString("\x1b\\\n")
+	return bw.Flush()
+}
+
+// writeSixelRun writes row using the sixel repeat introducer for runs
+func writeSixelRun(w *bufio.Writer, row []byte) {
+	for i := 0; i < len(row); {
+		j := i + 1
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, row[i])
+		} else {
+			for k := i; k < j; k++ {
+				w.WriteByte(row[k])
+			}
+		}
+		i = j
+	}
+}
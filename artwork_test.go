@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codegoalie/stream-player/artwork"
+	"github.com/codegoalie/stream-player/models"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from downloads
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitForArtwork waits until a has downloaded artwork to show
+func waitForArtwork(t *testing.T, a *artworkDisplay) string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if path := a.current(); path != "" {
+			return path
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("artwork never arrived")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestArtworkDropsStaleDownloads(t *testing.T) {
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow.png" {
+			<-release
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(img.Bytes())
+	}))
+	defer server.Close()
+	defer close(release)
+
+	dir, err := ioutil.TempDir("", "artwork")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := &syncBuffer{}
+	a := &artworkDisplay{cache: &artwork.Cache{Dir: dir}, protocol: artwork.Blocks, out: out}
+
+	// the first track's artwork is still downloading when the track changes
+	a.show(&models.TrackInfo{Title: "Grand Canyon Suite", ArtURL: server.URL + "/slow.png"})
+	if path := a.current(); path != "" {
+		t.Errorf("artwork %q is shown before it downloads", path)
+	}
+	a.show(&models.TrackInfo{Title: "Main Street Electrical Parade", ArtURL: server.URL + "/fast.png"})
+	path := waitForArtwork(t, a)
+	drawn := out.String()
+	if lines := strings.Count(drawn, "\n"); lines != artworkRows {
+		t.Errorf("drew %d lines, want %d", lines, artworkRows)
+	}
+
+	release <- struct{}{}
+	time.Sleep(50 * time.Millisecond)
+	if again := a.current(); again != path {
+		t.Errorf("stale artwork %q replaced %q", again, path)
+	}
+	if out.String() != drawn {
+		t.Error("stale artwork was drawn")
+	}
+
+	// the same artwork for the next track isn't drawn again
+	a.show(&models.TrackInfo{Title: "Electrical Parade Reprise", ArtURL: server.URL + "/fast.png"})
+	if out.String() != drawn {
+		t.Error("artwork was drawn twice")
+	}
+
+	a.show(&models.TrackInfo{Title: "Station ID"})
+	if path := a.current(); path != "" {
+		t.Errorf("artwork %q is still shown for a track without any", path)
+	}
+}
+
+func TestArtworkDisplayNil(t *testing.T) {
+	var a *artworkDisplay
+	a.show(&models.TrackInfo{ArtURL: "https://i.scdn.co/image/ab67616d0000b273a5b2c8f0e4d1c97b3f6e2d8a"})
+	if path := a.current(); path != "" {
+		t.Error("a nil display shows artwork")
+	}
+}
@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+
+	vlc "github.com/adrg/libvlc-go/v3"
+)
+
+// Device is an audio output device of one of VLC's output modules
+type Device struct {
+	Module            string
+	ModuleDescription string
+	ID                string
+	Description       string
+}
+
+// Devices lists the devices of every audio output module. vlc.Init must have
+// been called.
+func Devices() ([]Device, error) {
+	outputs, err := vlc.AudioOutputList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audio outputs: %w", err)
+	}
+
+	// devices are listed through a player, which isn't played
+	player, err := vlc.NewPlayer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create player to list audio devices: %w", err)
+	}
+	defer player.Release()
+
+	devices := []Device{}
+	for _, output := range outputs {
+		if err := player.SetAudioOutput(output.Name); err != nil {
+			continue
+		}
+		outputDevices, err := player.AudioOutputDevices()
+		if err != nil {
+			continue
+		}
+		for _, device := range outputDevices {
+			devices = append(devices, Device{
+				Module:            output.Name,
+				ModuleDescription: output.Description,
+				ID:                device.Name,
+				Description:       device.Description,
+			})
+		}
+	}
+
+	return devices, nil
+}
+
+// SetDevice switches player to device of module. Changing to another module
+// only takes effect the next time the player starts playing, while a device
+// of the current module is switched to immediately.
+func SetDevice(player *vlc.Player, module, device string) error {
+	if player == nil {
+		return errors.New("no player to set the audio device of")
+	}
+
+	if module != "" {
+		if err := player.SetAudioOutput(module); err != nil {
+			return fmt.Errorf("failed to set audio output module: %w", err)
+		}
+	}
+
+	if err := player.SetAudioOutputDevice(device, module); err != nil {
+		return fmt.Errorf("failed to set audio output device: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/rules"
+)
+
+const (
+	fullVolume          = 100
+	returnCheckInterval = 5 * time.Second
+	returnCheckTimeout  = 5 * time.Second
+)
+
+// trackChange is sent by the poller whenever a station starts a new track
+type trackChange struct {
+	station string
+	info    *models.TrackInfo
+}
+
+// returnCheck reports whether the station skipped away from has finished
+// playing its blocked track
+type returnCheck struct {
+	index   int
+	allowed bool
+}
+
+// blocklist applies rules to track changes and remembers what it changed so
+// it can be undone once the blocked track ends
+type blocklist struct {
+	engine  *rules.Engine
+	volumes chan<- int
+
+	// returnTo is the index of the station skipped away from or -1
+	returnTo  int
+	quieted   bool
+	checking  bool
+	checkedAt time.Time
+}
+
+func newBlocklist(engine *rules.Engine, volumes chan<- int) *blocklist {
+	return &blocklist{
+		engine:   engine,
+		volumes:  volumes,
+		returnTo: -1,
+	}
+}
+
+// trackChanged applies the first rule matching info while current is playing
+// and returns the index of the station to switch to or -1 to stay
+func (b *blocklist) trackChanged(info *models.TrackInfo, current int) int {
+	rule := b.engine.Match(info)
+	if rule == nil {
+		b.restoreVolume()
+		return -1
+	}
+
+	switch rule.Action {
+	case rules.Skip:
+		b.restoreVolume()
+		if b.returnTo < 0 {
+			b.returnTo = current
+		}
+		return (current + 1) % len(medias)
+	case rules.Mute:
+		b.quieted = true
+		b.volumes <- 0
+	case rules.Lower:
+		b.quieted = true
+		b.volumes <- rule.Volume
+	}
+
+	return -1
+}
+
+// reset forgets any pending return, e.g. after the user picks a station
+func (b *blocklist) reset() {
+	b.restoreVolume()
+	b.returnTo = -1
+}
+
+// checkReturn fetches the station skipped away from in the background, at
+// most once per returnCheckInterval, and reports on results whether it is
+// safe to go back
+func (b *blocklist) checkReturn(results chan<- returnCheck) {
+	if b.returnTo < 0 || b.checking || time.Since(b.checkedAt) < returnCheckInterval {
+		return
+	}
+
+	b.checking = true
+	b.checkedAt = time.Now()
+	index := b.returnTo
+	go func() {
+		result := fetchNowPlaying(medias[index], returnCheckTimeout)
+		results <- returnCheck{
+			index:   index,
+			allowed: result.Error == "" && b.engine.Match(result.info) == nil,
+		}
+	}()
+}
+
+// checked records a finished returnCheck and returns the index of the
+// station to switch back to or -1 to stay
+func (b *blocklist) checked(check returnCheck) int {
+	b.checking = false
+	if !check.allowed || check.index != b.returnTo {
+		return -1
+	}
+
+	b.returnTo = -1
+	return check.index
+}
+
+func (b *blocklist) restoreVolume() {
+	if !b.quieted {
+		return
+	}
+
+	b.quieted = false
+	b.volumes <- fullVolume
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/rules"
+)
+
+func newTestBlocklist(t *testing.T) (*blocklist, chan int) {
+	t.Helper()
+	engine, err := rules.New(
+		&rules.Rule{MediaType: "^(COM|NWS|INT)$", Action: rules.Skip},
+		&rules.Rule{Title: "(?i)station id", Action: rules.Mute},
+		&rules.Rule{Artist: "(?i)^dpark radio$", Action: rules.Lower, Volume: 30},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumes := make(chan int, 10)
+	return newBlocklist(engine, volumes), volumes
+}
+
+// sent drains the volumes set so far
+func sent(volumes chan int) []int {
+	set := []int{}
+	for {
+		select {
+		case volume := <-volumes:
+			set = append(set, volume)
+		default:
+			return set
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBlocklistTrackChanged(t *testing.T) {
+	music := &models.TrackInfo{Title: "Grand Canyon Suite", MediaType: "MUS"}
+	commercial := &models.TrackInfo{Title: "Visit Orlando", MediaType: "COM"}
+	stationID := &models.TrackInfo{Title: "Station ID", MediaType: "MUS"}
+	promo := &models.TrackInfo{Title: "Promo", Artist: "DPark Radio"}
+	last := len(medias) - 1
+
+	cases := []struct {
+		name     string
+		tracks   []*models.TrackInfo
+		current  int
+		want     int
+		volumes  []int
+		returnTo int
+	}{
+		{"music plays", []*models.TrackInfo{music}, 0, -1, []int{}, -1},
+		{"commercial skips", []*models.TrackInfo{commercial}, 2, 3, []int{}, 2},
+		{"skip wraps around", []*models.TrackInfo{commercial}, last, 0, []int{}, last},
+		{"station ID mutes", []*models.TrackInfo{stationID}, 0, -1, []int{0}, -1},
+		{"promo lowers", []*models.TrackInfo{promo}, 0, -1, []int{30}, -1},
+		{"music restores the volume", []*models.TrackInfo{promo, music}, 0, -1, []int{30, fullVolume}, -1},
+		{"skipping restores the volume", []*models.TrackInfo{stationID, commercial}, 0, 1, []int{0, fullVolume}, 0},
+		{"unquieted music sets no volume", []*models.TrackInfo{music, music}, 0, -1, []int{}, -1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b, volumes := newTestBlocklist(t)
+			var got int
+			for _, track := range c.tracks {
+				got = b.trackChanged(track, c.current)
+			}
+			if got != c.want {
+				t.Errorf("switched to %d, want %d", got, c.want)
+			}
+			if set := sent(volumes); !equalInts(set, c.volumes) {
+				t.Errorf("set volumes %v, want %v", set, c.volumes)
+			}
+			if b.returnTo != c.returnTo {
+				t.Errorf("returning to %d, want %d", b.returnTo, c.returnTo)
+			}
+		})
+	}
+}
+
+func TestBlocklistReturns(t *testing.T) {
+	b, _ := newTestBlocklist(t)
+	commercial := &models.TrackInfo{MediaType: "COM"}
+
+	// skipping again from the next station still returns to the first
+	next := b.trackChanged(commercial, 2)
+	b.trackChanged(commercial, next)
+	if b.returnTo != 2 {
+		t.Fatalf("returning to %d, want 2", b.returnTo)
+	}
+
+	b.checking = true
+	if index := b.checked(returnCheck{index: 2, allowed: false}); index != -1 {
+		t.Errorf("returned to %d while it was still blocked", index)
+	}
+	if b.checking {
+		t.Error("still checking after a check finished")
+	}
+	if index := b.checked(returnCheck{index: 5, allowed: true}); index != -1 {
+		t.Errorf("returned to %d, which wasn't skipped away from", index)
+	}
+	if index := b.checked(returnCheck{index: 2, allowed: true}); index != 2 {
+		t.Errorf("returned to %d, want 2", index)
+	}
+	if b.returnTo != -1 {
+		t.Errorf("still returning to %d after going back", b.returnTo)
+	}
+}
+
+func TestBlocklistReset(t *testing.T) {
+	b, volumes := newTestBlocklist(t)
+	b.trackChanged(&models.TrackInfo{MediaType: "COM"}, 0)
+	b.trackChanged(&models.TrackInfo{Title: "Station ID"}, 1)
+	sent(volumes)
+
+	b.reset()
+	if b.returnTo != -1 {
+		t.Errorf("returning to %d after a reset", b.returnTo)
+	}
+	if set := sent(volumes); !equalInts(set, []int{fullVolume}) {
+		t.Errorf("set volumes %v, want the volume restored", set)
+	}
+	if index := b.checked(returnCheck{index: 0, allowed: true}); index != -1 {
+		t.Errorf("returned to %d after a reset", index)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codegoalie/stream-player/cassette"
+	"github.com/codegoalie/stream-player/utils"
+)
+
+// setUpCassette records track info responses to recordPath or replays them
+// from replayPath. When recording, it returns the Recorder to close on exit.
+// When replaying, it returns the URL of the audio to play in place of every
+// station: audioPath, or a chime when that's empty.
+func setUpCassette(recordPath, replayPath, audioPath string) (string, *cassette.Recorder, error) {
+	if recordPath != "" && replayPath != "" {
+		return "", nil, errors.New("-record and -replay can't be used together")
+	}
+
+	if recordPath != "" {
+		recorder, err := cassette.NewRecorder(recordPath, utils.DefaultHTTPClient.Transport)
+		if err != nil {
+			return "", nil, err
+		}
+		utils.DefaultHTTPClient.Transport = recorder
+		return "", recorder, nil
+	}
+
+	if replayPath == "" {
+		return "", nil, nil
+	}
+
+	replayer, err := cassette.Load(replayPath)
+	if err != nil {
+		return "", nil, err
+	}
+	utils.DefaultHTTPClient.Transport = replayer
+
+	if audioPath == "" {
+		audioPath = filepath.Join(os.TempDir(), "stream-player-demo.wav")
+		if err := cassette.WriteDemoAudio(audioPath); err != nil {
+			return "", nil, err
+		}
+	}
+	audioPath, err = filepath.Abs(audioPath)
+	if err != nil {
+		return "", nil, err
+	}
+	path := filepath.ToSlash(audioPath)
+	if !strings.HasPrefix(path, "/") {
+		// Windows paths start with a drive letter
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String(), nil, nil
+}
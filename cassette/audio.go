@@ -0,0 +1,60 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+const (
+	demoSampleRate = 22050
+	demoNoteLength = 1.5
+	demoVolume     = 0.2
+)
+
+// demoNotes are the frequencies of a C major arpeggio played by the demo
+// audio
+var demoNotes = []float64{523.25, 659.25, 783.99, 1046.50}
+
+// WriteDemoAudio writes a short, quiet chime as a WAV file to path, for
+// playing in place of a station's stream when nothing better is at hand
+func WriteDemoAudio(path string) error {
+	samplesPerNote := int(demoSampleRate * demoNoteLength)
+	samples := make([]int16, 0, samplesPerNote*len(demoNotes))
+	for _, freq := range demoNotes {
+		for i := 0; i < samplesPerNote; i++ {
+			t := float64(i) / demoSampleRate
+			// fade each note out like a struck bell
+			amplitude := demoVolume * math.Exp(-3*t)
+			samples = append(samples, int16(amplitude*math.MaxInt16*math.Sin(2*math.Pi*freq*t)))
+		}
+	}
+
+	var buf bytes.Buffer
+	dataSize := uint32(len(samples) * 2)
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	for _, field := range []interface{}{
+		uint32(16),                 // fmt chunk size
+		uint16(1),                  // PCM
+		uint16(1),                  // mono
+		uint32(demoSampleRate),     // sample rate
+		uint32(demoSampleRate * 2), // byte rate
+		uint16(2),                  // block align
+		uint16(16),                 // bits per sample
+	} {
+		binary.Write(&buf, binary.LittleEndian, field)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	binary.Write(&buf, binary.LittleEndian, samples)
+
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		err = fmt.Errorf("failed to write demo audio: %w", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Package cassette records HTTP responses to a file and replays them later
+// on the same timeline, so the player can run without reaching the
+// stations' servers.
+package cassette
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxRecordedBytes is the largest body recorded. Anything bigger, such as an
+// audio stream mislabeled as text, is passed along without recording it.
+const maxRecordedBytes = 1 << 20
+
+// recordedHeaders are the response headers worth keeping. Date lets the
+// player line replayed track times up with the replay's clock.
+var recordedHeaders = []string{"Content-Type", "Date", "ETag", "Last-Modified"}
+
+// Interaction is one recorded response, stored as a line of JSON
+type Interaction struct {
+	At     time.Time         `json:"at"`
+	URL    string            `json:"url"`
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   string            `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper which saves the track info responses it
+// passes along to a cassette file. Anything else, like artwork and audio
+// streams, passes through untouched.
+type Recorder struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecorder appends responses from next to the cassette at path
+func NewRecorder(path string, next http.RoundTripper) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		err = fmt.Errorf("failed to open cassette: %w", err)
+		return nil, err
+	}
+	return &Recorder{next: next, file: file}, nil
+}
+
+// RoundTrip makes the request and records the response if it's JSON or
+// text. Requests are made unconditionally so every recorded response has a
+// body to replay.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if !isTrackInfo(resp) {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRecordedBytes+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxRecordedBytes || !utf8.Valid(body) {
+		// too big or not really text, so hand it on as it arrives
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		At:     time.Now(),
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: map[string]string{},
+		Body:   string(body),
+	}
+	for _, name := range recordedHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			interaction.Header[name] = value
+		}
+	}
+
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		err = fmt.Errorf("failed to record to cassette: %w", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// isTrackInfo reports whether resp looks like track info: JSON or text with
+// a known length small enough to record
+func isTrackInfo(resp *http.Response) bool {
+	if resp.ContentLength > maxRecordedBytes {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		strings.HasSuffix(mediaType, "+json")
+}
+
+// Close closes the cassette file. A nil Recorder does nothing.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// Replayer is an http.RoundTripper which answers requests from a cassette.
+// Each request gets the response recorded for its URL at the same time into
+// the recording as it is now into the replay, looping when the recording
+// runs out.
+type Replayer struct {
+	start    time.Time
+	length   time.Duration
+	recorded time.Time
+	byURL    map[string][]Interaction
+}
+
+// Load reads the cassette at path for replaying, starting now
+func Load(path string) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		err = fmt.Errorf("failed to open cassette: %w", err)
+		return nil, err
+	}
+	defer file.Close()
+
+	var interactions []Interaction
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			err = fmt.Errorf("failed to parse cassette line %d: %w", line, err)
+			return nil, err
+		}
+		interactions = append(interactions, interaction)
+	}
+	if err := scanner.Err(); err != nil {
+		err = fmt.Errorf("failed to read cassette: %w", err)
+		return nil, err
+	}
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("cassette %s is empty", path)
+	}
+
+	return newReplayer(interactions, time.Now()), nil
+}
+
+func newReplayer(interactions []Interaction, start time.Time) *Replayer {
+	sort.SliceStable(interactions, func(i, j int) bool {
+		return interactions[i].At.Before(interactions[j].At)
+	})
+
+	r := &Replayer{
+		start:    start,
+		recorded: interactions[0].At,
+		length:   interactions[len(interactions)-1].At.Sub(interactions[0].At),
+		byURL:    map[string][]Interaction{},
+	}
+	for _, interaction := range interactions {
+		key := normalize(interaction.URL)
+		r.byURL[key] = append(r.byURL[key], interaction)
+	}
+	return r
+}
+
+// RoundTrip answers req with the response recorded for its URL as of the
+// same point in the recording. URLs which were never recorded get a 404.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	interaction, ok := r.find(req.URL.String(), time.Now())
+	if !ok {
+		return response(req, http.StatusNotFound, nil, []byte("not in cassette: "+req.URL.String())), nil
+	}
+
+	return response(req, interaction.Status, interaction.Header, []byte(interaction.Body)), nil
+}
+
+// find picks the latest response for rawURL recorded no later into the
+// recording than now is into the replay, or the first one if there isn't
+// one yet
+func (r *Replayer) find(rawURL string, now time.Time) (Interaction, bool) {
+	interactions := r.byURL[normalize(rawURL)]
+	if len(interactions) == 0 {
+		return Interaction{}, false
+	}
+
+	elapsed := now.Sub(r.start)
+	if r.length > 0 {
+		elapsed %= r.length
+	}
+	at := r.recorded.Add(elapsed)
+
+	found := interactions[0]
+	for _, interaction := range interactions {
+		if interaction.At.After(at) {
+			break
+		}
+		found = interaction
+	}
+	return found, true
+}
+
+func response(req *http.Request, status int, header map[string]string, body []byte) *http.Response {
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	for name, value := range header {
+		resp.Header.Set(name, value)
+	}
+	return resp
+}
+
+// normalize drops the "_" query parameter stations add to bust caches, so
+// requests match recordings made at other times
+func normalize(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Del("_")
+	u.RawQuery = query.Encode()
+	return strings.TrimSuffix(u.String(), "?")
+}
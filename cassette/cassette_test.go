@@ -0,0 +1,129 @@
+package cassette
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/info":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Header().Set("ETag", `"1"`)
+			w.Write([]byte(`{"title":"Main Street Electrical Parade"}`))
+		case "/art.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte{0xff, 0xd8, 0xff})
+		case "/stream":
+			// an endless stream, which must not be read to the end
+			w.Header().Set("Content-Type", "audio/mpeg")
+			for {
+				if _, err := w.Write(make([]byte, 1024)); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "cassette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassette.jsonl")
+	recorder, err := NewRecorder(path, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: recorder, Timeout: 5 * time.Second}
+
+	for _, page := range []string{"/info?_=1", "/art.jpg"} {
+		resp, err := client.Get(server.URL + page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	stream, err := client.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Body.Read(make([]byte, 1024)); err != nil {
+		t.Fatal(err)
+	}
+	stream.Body.Close()
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayer.byURL) != 1 {
+		t.Errorf("recorded %d URLs, want only the track info", len(replayer.byURL))
+	}
+
+	// the cache busting parameter is ignored when matching
+	replay := &http.Client{Transport: replayer}
+	resp, err := replay.Get(server.URL + "/info?_=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != `{"title":"Main Street Electrical Parade"}` {
+		t.Errorf("replayed %q", body)
+	}
+	if etag := resp.Header.Get("ETag"); etag != `"1"` {
+		t.Errorf("replayed ETag %q", etag)
+	}
+}
+
+func TestReplayUnknownURL(t *testing.T) {
+	replayer := newReplayer([]Interaction{
+		{At: time.Now(), URL: "https://example.com/info", Status: http.StatusOK, Body: "{}"},
+	}, time.Now())
+
+	resp, err := (&http.Client{Transport: replayer}).Get("https://example.com/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("got %s, want 404", resp.Status)
+	}
+}
+
+func TestReplayFollowsRecordingTimeline(t *testing.T) {
+	recorded := time.Date(2021, 8, 11, 20, 0, 0, 0, time.UTC)
+	replayer := newReplayer([]Interaction{
+		{At: recorded, URL: "https://example.com/info", Body: "first"},
+		{At: recorded.Add(time.Minute), URL: "https://example.com/info", Body: "second"},
+		{At: recorded.Add(2 * time.Minute), URL: "https://example.com/other", Body: "end"},
+	}, recorded)
+
+	for _, test := range []struct {
+		elapsed time.Duration
+		want    string
+	}{
+		{0, "first"},
+		{90 * time.Second, "second"},
+		// the two minute recording loops
+		{150 * time.Second, "first"},
+	} {
+		got, ok := replayer.find("https://example.com/info", recorded.Add(test.elapsed))
+		if !ok || got.Body != test.want {
+			t.Errorf("after %v got %q, want %q", test.elapsed, got.Body, test.want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestSetUpCassetteRejectsRecordAndReplay(t *testing.T) {
+	if _, _, err := setUpCassette("record.jsonl", "replay.jsonl", ""); err == nil {
+		t.Error("expected an error recording and replaying at once")
+	}
+}
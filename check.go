@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/utils"
+)
+
+// firstBytes is how much of a stream must arrive for it to pass
+const firstBytes = 4096
+
+// playlistTypes are the content types HLS stations stream playlists as
+var playlistTypes = map[string]bool{
+	"application/vnd.apple.mpegurl": true,
+	"application/x-mpegurl":         true,
+	"audio/mpegurl":                 true,
+	"audio/x-mpegurl":               true,
+}
+
+// probe is the outcome of checking one of a station's URLs
+type probe struct {
+	Check     string  `json:"check"`
+	URL       string  `json:"url"`
+	Pass      bool    `json:"pass"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    string  `json:"detail"`
+}
+
+// stationHealth is every probe of a station
+type stationHealth struct {
+	Station string  `json:"station"`
+	Pass    bool    `json:"pass"`
+	Probes  []probe `json:"probes"`
+}
+
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	workers := fs.Int("workers", 4, "number of stations to check at once")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout for each stream and track info request")
+	_ = fs.Parse(args)
+
+	results := checkAllStations(medias, *workers, *timeout)
+	if err := printHealth(os.Stdout, results, *asJSON); err != nil {
+		log.Fatal("failed to print check results: ", err)
+	}
+
+	for _, result := range results {
+		if !result.Pass {
+			os.Exit(1)
+		}
+	}
+}
+
+// checkAllStations checks every source using at most workers stations at
+// once. Results are in the same order as sources.
+func checkAllStations(sources []models.MediaSource, workers int, timeout time.Duration) []stationHealth {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]stationHealth, len(sources))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = checkStation(sources[i], timeout)
+			}
+		}()
+	}
+
+	for i := range sources {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// checkStation opens each of source's streams and fetches its track info
+func checkStation(source models.MediaSource, timeout time.Duration) stationHealth {
+	result := stationHealth{Station: source.Name(), Pass: true}
+
+	urls := []string{source.StreamURL()}
+	if mirrors, ok := source.(models.MirrorProvider); ok {
+		urls = mirrors.StreamURLs()
+	}
+	for i, streamURL := range urls {
+		check := "stream"
+		if i > 0 {
+			check = fmt.Sprintf("mirror %d", i)
+		}
+		result.Probes = append(result.Probes, probeStream(check, streamURL, timeout))
+	}
+	result.Probes = append(result.Probes, probeInfo(source, timeout))
+
+	for _, p := range result.Probes {
+		result.Pass = result.Pass && p.Pass
+	}
+	return result
+}
+
+// probeStream passes when streamURL answers with audio, or a playlist for
+// HLS, and its first bytes arrive
+func probeStream(check, streamURL string, timeout time.Duration) probe {
+	result := probe{Check: check, URL: streamURL}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	resp, err := utils.DefaultClient.Open(ctx, streamURL)
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !(strings.HasPrefix(mediaType, "audio/") || mediaType == "application/ogg" || playlistTypes[mediaType]) {
+		result.Detail = fmt.Sprintf("not audio: content type %q", contentType)
+		return result
+	}
+
+	buf := make([]byte, firstBytes)
+	n, err := io.ReadFull(resp.Body, buf)
+	result.LatencyMS = milliseconds(time.Since(startedAt))
+	switch {
+	case n == 0:
+		result.Detail = fmt.Sprintf("no audio arrived: %v", err)
+		return result
+	case err != nil && !playlistTypes[mediaType]:
+		// playlists are short, but audio streams shouldn't end
+		result.Detail = fmt.Sprintf("stream ended after %d bytes: %v", n, err)
+		return result
+	}
+
+	result.Pass = true
+	result.Detail = mediaType
+	return result
+}
+
+// probeInfo passes when source's track info is fetched and parses into a
+// track with a title
+func probeInfo(source models.MediaSource, timeout time.Duration) probe {
+	result := probe{Check: "info", URL: source.InfoURL()}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	info, err := fetchTrackInfo(ctx, source)
+	result.LatencyMS = milliseconds(time.Since(startedAt))
+	switch {
+	case err != nil:
+		result.Detail = err.Error()
+		return result
+	case info.Title == "":
+		result.Detail = "track info has no title"
+		return result
+	}
+
+	result.Pass = true
+	result.Detail = info.Title
+	if info.Artist != "" {
+		result.Detail += " - " + info.Artist
+	}
+	return result
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Round(time.Millisecond)) / float64(time.Millisecond)
+}
+
+func printHealth(w io.Writer, results []stationHealth, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(results)
+	}
+
+	failed := 0
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATION\tCHECK\tRESULT\tLATENCY\tDETAIL")
+	for _, result := range results {
+		if !result.Pass {
+			failed++
+		}
+		for i, p := range result.Probes {
+			station := ""
+			if i == 0 {
+				station = result.Station
+			}
+			status := "PASS"
+			if !p.Pass {
+				status = "FAIL"
+			}
+			latency := "-"
+			if p.Pass || p.LatencyMS > 0 {
+				latency = fmt.Sprintf("%.fms", p.LatencyMS)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", station, p.Check, status, latency, p.Detail)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%d of %d stations passed\n", len(results)-failed, len(results))
+	return err
+}
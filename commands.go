@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codegoalie/stream-player/audio"
+)
+
+// defaultRewind is how far rewind and forward move without a duration
+const defaultRewind = 30 * time.Second
+
+// commands runs commands for things without a media key, typed on stdin or
+// sent over MQTT:
+//
+//	next               skip to the next station
+//	previous           go back to the previous station
+//	station NAME       switch to the station whose name starts with NAME
+//	volume PERCENT     set the volume, from 0 to 200
+//	device NAME        switch to an audio device listed by the devices subcommand
+//	pause              pause or resume, with -timeshift
+//	rewind [DURATION]  go back 30s or DURATION, with -timeshift
+//	forward [DURATION] go forward 30s or DURATION, with -timeshift
+//	live               jump back to the live stream, with -timeshift
+type commands struct {
+	// out is where problems with commands are reported
+	out      io.Writer
+	actions  chan<- mediaAction
+	stations chan<- int
+	volumes  chan<- int
+	devices  chan<- audio.Device
+	rewinds  chan<- time.Duration
+}
+
+// read runs commands from r, one per line
+func (c commands) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		c.run(scanner.Text())
+	}
+}
+
+// run runs a single command
+func (c commands) run(line string) {
+	fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+	arg := ""
+	if len(fields) > 1 {
+		arg = strings.TrimSpace(fields[1])
+	}
+
+	name := strings.ToLower(fields[0])
+	switch name {
+	case "":
+	case "next":
+		c.actions <- nextMediaAction
+	case "previous", "prev":
+		c.actions <- previousMediaAction
+	case "station":
+		index, err := stationIndex(arg)
+		if err != nil {
+			fmt.Fprintln(c.out, "Error: "+err.Error())
+			return
+		}
+		c.stations <- index
+	case "volume":
+		volume, err := strconv.Atoi(strings.TrimSuffix(arg, "%"))
+		if err != nil || volume < 0 || volume > maxVolume {
+			fmt.Fprintf(c.out, "usage: volume PERCENT, from 0 to %d\n", maxVolume)
+			return
+		}
+		c.volumes <- volume
+	case "device":
+		if arg == "" {
+			fmt.Fprintln(c.out, "usage: device NAME")
+			return
+		}
+		device, err := findDevice(arg)
+		if err != nil {
+			fmt.Fprintln(c.out, "Error: "+err.Error())
+			return
+		}
+		c.devices <- device
+	case "pause", "play":
+		c.actions <- playPauseMediaAction
+	case "rewind", "forward":
+		d := defaultRewind
+		if arg != "" {
+			var err error
+			d, err = time.ParseDuration(arg)
+			if err != nil {
+				fmt.Fprintln(c.out, "Error: "+err.Error())
+				return
+			}
+		}
+		if name == "forward" {
+			d = -d
+		}
+		c.rewinds <- d
+	case "live":
+		c.actions <- liveMediaAction
+	default:
+		fmt.Fprintf(c.out, "unknown command %q\n", fields[0])
+	}
+}
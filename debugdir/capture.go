@@ -0,0 +1,244 @@
+// Package debugdir keeps what's needed to diagnose track info problems after
+// the fact: every failing request and response, saved to its own file, and a
+// rotating log of what the poller did.
+package debugdir
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxBodyBytes is how much of each response body is kept
+	maxBodyBytes = 1 << 20
+	// exchangeTTL is how long a response is kept for a failure to be
+	// saved with
+	exchangeTTL = 5 * time.Minute
+	// captureSuffix ends the name of every saved failure
+	captureSuffix = ".json"
+)
+
+// Capture is a failing request and its response, saved as a JSON file
+type Capture struct {
+	At      time.Time   `json:"at"`
+	Station string      `json:"station"`
+	URL     string      `json:"url"`
+	Status  int         `json:"status,omitempty"`
+	Header  http.Header `json:"header,omitempty"`
+	// Body is the response body when it's text, or else BodyBase64 holds
+	// it
+	Body       string `json:"body,omitempty"`
+	BodyBase64 string `json:"body_base64,omitempty"`
+	Error      string `json:"error"`
+}
+
+// Payload is the response body
+func (c *Capture) Payload() ([]byte, error) {
+	if c.BodyBase64 != "" {
+		return base64.StdEncoding.DecodeString(c.BodyBase64)
+	}
+	return []byte(c.Body), nil
+}
+
+// exchange is the most recent response for a URL
+type exchange struct {
+	at     time.Time
+	status int
+	header http.Header
+	body   []byte
+}
+
+// Recorder is an http.RoundTripper which remembers the latest response for
+// each URL, so a request which then fails to parse can be saved along with
+// it
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu        sync.Mutex
+	exchanges map[string]exchange
+}
+
+// New saves failures to dir, creating it if needed, from requests made
+// with next
+func New(dir string, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		err = fmt.Errorf("failed to create debug directory: %w", err)
+		return nil, err
+	}
+	return &Recorder{dir: dir, next: next, exchanges: map[string]exchange{}}, nil
+}
+
+// Dir is where failures are saved
+func (r *Recorder) Dir() string {
+	if r == nil {
+		return ""
+	}
+	return r.dir
+}
+
+// RoundTrip makes the request, keeping the response once its body has been
+// read and closed
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rawURL := req.URL.String()
+	kept := exchange{at: time.Now(), status: resp.StatusCode, header: resp.Header.Clone()}
+	resp.Body = &teeBody{
+		ReadCloser: resp.Body,
+		onClose: func(body []byte) {
+			kept.body = body
+			r.keep(rawURL, kept)
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) keep(rawURL string, kept exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for u, old := range r.exchanges {
+		if kept.at.Sub(old.at) > exchangeTTL {
+			delete(r.exchanges, u)
+		}
+	}
+	r.exchanges[rawURL] = kept
+}
+
+// Save writes a failure fetching station's track info from rawURL to a new
+// file in the directory, returning its path. body is the payload which
+// failed to parse, if the request itself succeeded; otherwise the last
+// response for rawURL is saved.
+func (r *Recorder) Save(station, rawURL string, body []byte, failure error) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+
+	capture := Capture{At: time.Now(), Station: station, URL: rawURL}
+	if failure != nil {
+		capture.Error = failure.Error()
+	}
+
+	r.mu.Lock()
+	kept, ok := r.exchanges[rawURL]
+	r.mu.Unlock()
+	if ok {
+		capture.Status = kept.status
+		capture.Header = kept.header
+		if body == nil {
+			body = kept.body
+		}
+	}
+	if utf8.Valid(body) {
+		capture.Body = string(body)
+	} else {
+		capture.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+
+	encoded, err := json.MarshalIndent(capture, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := capture.At.Format("20060102-150405.000") + "-" + slug(station) + captureSuffix
+	path := filepath.Join(r.dir, name)
+	if err := ioutil.WriteFile(path, encoded, 0644); err != nil {
+		err = fmt.Errorf("failed to save debug capture: %w", err)
+		return "", err
+	}
+	return path, nil
+}
+
+// List finds the saved failures in dir, oldest first
+func List(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		err = fmt.Errorf("failed to read debug directory: %w", err)
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), captureSuffix) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	// names start with the time they were saved
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Load reads a saved failure
+func Load(path string) (*Capture, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("failed to read debug capture: %w", err)
+		return nil, err
+	}
+
+	capture := &Capture{}
+	if err := json.Unmarshal(raw, capture); err != nil {
+		err = fmt.Errorf("failed to parse debug capture %s: %w", path, err)
+		return nil, err
+	}
+	return capture, nil
+}
+
+// slug makes a station name safe to use in a file name
+func slug(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// teeBody keeps a copy of what's read from a response body, up to
+// maxBodyBytes, and hands it to onClose
+type teeBody struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	onClose func([]byte)
+	closed  bool
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if room := maxBodyBytes - t.buf.Len(); room > 0 {
+		if n < room {
+			room = n
+		}
+		t.buf.Write(p[:room])
+	}
+	return n, err
+}
+
+func (t *teeBody) Close() error {
+	if !t.closed {
+		t.closed = true
+		t.onClose(t.buf.Bytes())
+	}
+	return t.ReadCloser.Close()
+}
@@ -0,0 +1,128 @@
+package debugdir
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxLogBytes is how large the log grows before it's rotated
+	DefaultMaxLogBytes = 1 << 20
+	// DefaultKeepLogs is how many rotated logs are kept
+	DefaultKeepLogs = 3
+)
+
+// Fields are the details of a logged event
+type Fields map[string]interface{}
+
+// Log writes events as lines of JSON, moving the file aside to path.1,
+// path.2 and so on once it reaches MaxBytes
+type Log struct {
+	MaxBytes int64
+	Keep     int
+
+	path string
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenLog appends to the log at path
+func OpenLog(path string) (*Log, error) {
+	l := &Log{MaxBytes: DefaultMaxLogBytes, Keep: DefaultKeepLogs, path: path}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		err = fmt.Errorf("failed to open debug log: %w", err)
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		err = fmt.Errorf("failed to open debug log: %w", err)
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// Event logs that something happened, along with its fields. Errors are
+// logged as their message. Problems writing the log are ignored so they
+// never interrupt playback.
+func (l *Log) Event(event string, fields Fields) {
+	if l == nil {
+		return
+	}
+
+	entry := map[string]interface{}{}
+	for name, value := range fields {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		entry[name] = value
+	}
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["event"] = event
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return
+	}
+	if l.MaxBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.MaxBytes {
+		if err := l.rotate(); err != nil {
+			return
+		}
+	}
+	n, _ := l.file.Write(line)
+	l.size += int64(n)
+}
+
+// rotate moves each log aside to the next number, dropping the oldest, and
+// starts a new one. l.mu must be held.
+func (l *Log) rotate() error {
+	l.file.Close()
+	l.file = nil
+
+	for i := l.Keep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	if l.Keep > 0 {
+		_ = os.Rename(l.path, l.path+".1")
+	} else {
+		_ = os.Remove(l.path)
+	}
+	return l.open()
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
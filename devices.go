@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	vlc "github.com/adrg/libvlc-go/v3"
+	"github.com/codegoalie/stream-player/audio"
+)
+
+func runDevices(args []string) {
+	fs := flag.NewFlagSet("devices", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	if err := vlc.Init("--quiet"); err != nil {
+		log.Fatal("failed to init vlc: ", err)
+	}
+	defer vlc.Release()
+
+	devices, err := audio.Devices()
+	if err != nil {
+		log.Fatal("failed to list audio devices: ", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DEVICE\tDESCRIPTION")
+	for _, device := range devices {
+		fmt.Fprintf(tw, "%s:%s\t%s\n", device.Module, device.ID, device.Description)
+	}
+	_ = tw.Flush()
+}
+
+// findDevice looks up a device by "module:id" as listed by the devices
+// subcommand or by just its ID or description
+func findDevice(name string) (audio.Device, error) {
+	devices, err := audio.Devices()
+	if err != nil {
+		return audio.Device{}, fmt.Errorf("failed to list audio devices: %w", err)
+	}
+
+	for _, device := range devices {
+		if name == device.Module+":"+device.ID || name == device.ID {
+			return device, nil
+		}
+	}
+	for _, device := range devices {
+		if strings.EqualFold(name, device.Description) {
+			return device, nil
+		}
+	}
+
+	return audio.Device{}, fmt.Errorf("unknown audio device %q, see the devices subcommand", name)
+}
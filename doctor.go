@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/codegoalie/stream-player/debugdir"
+	"github.com/codegoalie/stream-player/models"
+)
+
+// debugCaptures saves failing track info requests when -debug-dir is given
+var debugCaptures *debugdir.Recorder
+
+// saveFailure keeps a failed track info fetch for the doctor subcommand.
+// Saving is best effort, so problems are only logged.
+func saveFailure(station, infoURL string, body []byte, failure error) {
+	if _, err := debugCaptures.Save(station, infoURL, body, failure); err != nil {
+		log.Print(err)
+	}
+}
+
+func runDoctor(args []string, debugDir string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	_ = fs.Parse(args)
+
+	dir := debugDir
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if dir == "" {
+		log.Fatal("doctor needs -debug-dir or a directory of saved failures")
+	}
+
+	paths, err := debugdir.List(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	parsed, failing := 0, 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSTATION\tRESULT")
+	for _, path := range paths {
+		capture, err := debugdir.Load(path)
+		if err != nil {
+			fmt.Fprintf(tw, "%s\t\t%s\n", filepath.Base(path), err)
+			continue
+		}
+
+		result, ok := diagnose(capture)
+		if ok {
+			parsed++
+		} else {
+			failing++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", filepath.Base(path), capture.Station, result)
+	}
+	_ = tw.Flush()
+
+	fmt.Printf("%d of %d saved payloads parse now\n", parsed, parsed+failing)
+	if failing > 0 {
+		os.Exit(1)
+	}
+}
+
+// diagnose replays a saved failure through its station's current parser
+func diagnose(capture *debugdir.Capture) (string, bool) {
+	var station models.MediaSource
+	for _, media := range medias {
+		if media.Name() == capture.Station {
+			station = media
+		}
+	}
+	if station == nil {
+		return "unknown station", false
+	}
+
+	payload, err := capture.Payload()
+	if err != nil {
+		return "unreadable payload: " + err.Error(), false
+	}
+	if len(payload) == 0 {
+		return "no payload, failed with: " + capture.Error, false
+	}
+
+	info, err := parseSafely(station, payload)
+	if err != nil {
+		return "still fails: " + err.Error(), false
+	}
+	if info == nil {
+		return "still fails: " + errEmptyMetadata.Error(), false
+	}
+	if info.Artist == "" {
+		return "parses: " + info.Title, true
+	}
+	return "parses: " + info.Title + " - " + info.Artist, true
+}
+
+// parseSafely reports a parser which panics as an error
+func parseSafely(station models.MediaSource, payload []byte) (info *models.TrackInfo, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("parser panicked: %v", p)
+		}
+	}()
+	return station.ParseTrackInfo(payload)
+}
@@ -10,8 +10,11 @@ import (
 const backgroundName = "Background (DPark Radio)"
 const backgroundStreamURL = "https://cheetah.streemlion.com/dparkradiobackground?1628709340894"
 const backgroundInfoURL = "https://c5.radioboss.fm/w/nowplayinginfo?u=38&_="
+const backgroundMirrorURL = "https://str2b.openstream.co/578?aw_0_1st.collectionid=3127&aw_0_1st.publisherId=602"
 
-// const backgroundStreamURL = "https://str2b.openstream.co/578?aw_0_1st.collectionid=3127&aw_0_1st.publisherId=602"
+const backgroundDescription = "Background music from around the parks"
+const backgroundHomepage = "https://www.dparkradio.com/dparkradioplayerbm.html"
+const backgroundGenre = "Theme Park Background Music"
 
 // Background streams the background music channel from DPark Radio
 type Background struct{}
@@ -26,6 +29,11 @@ func (b Background) StreamURL() string {
 	return backgroundStreamURL
 }
 
+// StreamURLs lists the current URL to stream audio followed by its mirrors
+func (b Background) StreamURLs() []string {
+	return []string{backgroundStreamURL, backgroundMirrorURL}
+}
+
 // InfoURL is the URL to fetch track data
 func (b Background) InfoURL() string {
 	return backgroundInfoURL + fmt.Sprintf("%d", time.Now().Unix())
@@ -33,5 +41,17 @@ func (b Background) InfoURL() string {
 
 // ParseTrackInfo parses the provided bytes into a TrackInfo
 func (b Background) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
-	return parseTrackInfo(raw)
+	return parseTrackInfo(backgroundName, raw)
+}
+
+func (b Background) Description() string {
+	return backgroundDescription
+}
+
+func (b Background) Homepage() string {
+	return backgroundHomepage
+}
+
+func (b Background) Genre() string {
+	return backgroundGenre
 }
@@ -8,6 +8,10 @@ const christmasName = "Christmas (DPark Radio)"
 const christmasStreamURL = "https://listen.openstream.co/4287/;?1631785016772"
 const christmasInfoURL = "https://c11.radioboss.fm/w/nowplayinginfo?u=39"
 
+const christmasDescription = "Holiday music from the parks and resorts"
+const christmasHomepage = "https://www.dparkradio.com"
+const christmasGenre = "Holiday"
+
 // Christmas streams the christmas music channel from DPark Radio
 type Christmas struct{}
 
@@ -28,5 +32,17 @@ func (b Christmas) InfoURL() string {
 
 // ParseTrackInfo parses the provided bytes into a TrackInfo
 func (b Christmas) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
-	return parseTrackInfo(raw)
+	return parseTrackInfo(christmasName, raw)
+}
+
+func (b Christmas) Description() string {
+	return christmasDescription
+}
+
+func (b Christmas) Homepage() string {
+	return christmasHomepage
+}
+
+func (b Christmas) Genre() string {
+	return christmasGenre
 }
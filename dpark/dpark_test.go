@@ -0,0 +1,46 @@
+package dpark
+
+import (
+	"testing"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/sourcetest"
+)
+
+func TestStations(t *testing.T) {
+	nowPlaying := sourcetest.ReadGolden(t, "RadioBoss now playing", "testdata/nowplayinginfo.json", models.TrackInfo{
+		Title:  "Main Street Electrical Parade",
+		Album:  "Magic Kingdom",
+		Artist: "Disneyland Band",
+	})
+	newlines := sourcetest.ReadGolden(t, "RadioBoss now playing with nl=1", "testdata/nowplayinginfo_nl.json", models.TrackInfo{
+		Title:  "Lobby Loop 2019",
+		Album:  "Disneyland Hotel",
+		Artist: "Disneyland Resort",
+	})
+
+	cases := []struct {
+		source  models.MediaSource
+		options sourcetest.Options
+	}{
+		{Background{}, sourcetest.Options{CacheBusting: true, Golden: []sourcetest.Golden{nowPlaying}}},
+		{Christmas{}, sourcetest.Options{Golden: []sourcetest.Golden{nowPlaying}}},
+		{Resort{}, sourcetest.Options{Golden: []sourcetest.Golden{nowPlaying, newlines}}},
+	}
+	for _, c := range cases {
+		t.Run(c.source.Name(), func(t *testing.T) {
+			sourcetest.Run(t, c.source, c.options)
+		})
+	}
+}
+
+// A null payload used to panic by unmarshaling into a nil response
+func TestParseTrackInfoNull(t *testing.T) {
+	info, err := parseTrackInfo(backgroundName, []byte("null"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "" {
+		t.Errorf("title is %q, want empty", info.Title)
+	}
+}
@@ -8,6 +8,10 @@ const resortName = "Resort TV (DPark Radio)"
 const resortStreamURL = "https://cheetah.streemlion.com/Channel4?1631622328219"
 const resortInfoURL = "https://c7.radioboss.fm/w/nowplayinginfo?u=208&nl=1&_=1605627484420"
 
+const resortDescription = "Music from the resort hotel TV channels"
+const resortHomepage = "https://www.dparkradio.com"
+const resortGenre = "Theme Park Background Music"
+
 // Resort streams the resort TV music channel from DPark Radio
 type Resort struct{}
 
@@ -28,5 +32,17 @@ func (b Resort) InfoURL() string {
 
 // ParseTrackInfo parses the provided bytes into a TrackInfo
 func (b Resort) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
-	return parseTrackInfo(raw)
+	return parseTrackInfo(resortName, raw)
+}
+
+func (b Resort) Description() string {
+	return resortDescription
+}
+
+func (b Resort) Homepage() string {
+	return resortHomepage
+}
+
+func (b Resort) Genre() string {
+	return resortGenre
 }
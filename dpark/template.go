@@ -0,0 +1,170 @@
+package dpark
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// Template parses a RadioBoss "nowplaying" string into track fields
+type Template struct {
+	name    string
+	fields  []string
+	pattern *regexp.Regexp
+}
+
+// Named templates for the formats DPark Radio stations use
+var (
+	AlbumTitleArtist = newFieldTemplate("album-title-artist", "album", "title", "artist")
+	ArtistTitle      = newFieldTemplate("artist-title", "artist", "title")
+	TitleArtist      = newFieldTemplate("title-artist", "title", "artist")
+	TitleOnly        = newFieldTemplate("title", "title")
+)
+
+var namedTemplates = []*Template{AlbumTitleArtist, ArtistTitle, TitleArtist, TitleOnly}
+
+// defaultTemplates are tried in order until one matches
+var defaultTemplates = []*Template{AlbumTitleArtist, ArtistTitle, TitleOnly}
+
+var stationTemplates = map[string][]*Template{}
+
+// newFieldTemplate matches fields separated by " - ". Every field but the
+// title matches as little as possible, so a title containing " - " stays
+// whole.
+func newFieldTemplate(name string, fields ...string) *Template {
+	groups := make([]string, len(fields))
+	for i, field := range fields {
+		if field == "title" {
+			groups[i] = "(?P<title>.+)"
+		} else {
+			groups[i] = "(?P<" + field + ">.+?)"
+		}
+	}
+
+	return &Template{
+		name:    name,
+		fields:  fields,
+		pattern: regexp.MustCompile("^" + strings.Join(groups, " - ") + "$"),
+	}
+}
+
+// ParseTemplate finds the named template or compiles s as a regular
+// expression with a "title" group and optional "artist" and "album" groups
+func ParseTemplate(s string) (*Template, error) {
+	for _, t := range namedTemplates {
+		if t.name == s {
+			return t, nil
+		}
+	}
+
+	pattern, err := regexp.Compile(s)
+	if err != nil {
+		err = fmt.Errorf("failed to compile DPark template: %w", err)
+		return nil, err
+	}
+
+	hasTitle := false
+	for _, name := range pattern.SubexpNames() {
+		switch name {
+		case "title":
+			hasTitle = true
+		case "", "artist", "album":
+		default:
+			return nil, fmt.Errorf("unknown DPark template group %q", name)
+		}
+	}
+	if !hasTitle {
+		return nil, fmt.Errorf("DPark template %q has no title group", s)
+	}
+
+	return &Template{name: s, pattern: pattern}, nil
+}
+
+// SetTemplates replaces the templates tried, in order, for the station named
+// name. It must be called before the station's track info is parsed.
+func SetTemplates(name string, templates ...*Template) {
+	stationTemplates[name] = templates
+}
+
+// StationNames lists the names of DPark Radio stations which accept templates
+func StationNames() []string {
+	return []string{backgroundName, christmasName, resortName}
+}
+
+func templatesFor(name string) []*Template {
+	if templates, ok := stationTemplates[name]; ok {
+		return templates
+	}
+
+	return defaultTemplates
+}
+
+// String is the template's name or pattern
+func (t *Template) String() string {
+	return t.name
+}
+
+// parse fills info from nowPlaying and reports whether it matched. The nl=1
+// variant of the API puts each field on its own line, which is matched
+// exactly when the template lists its fields.
+func (t *Template) parse(nowPlaying string, info *models.TrackInfo) bool {
+	if t.fields != nil && strings.Contains(nowPlaying, "\n") {
+		lines := splitLines(nowPlaying)
+		if len(lines) != len(t.fields) {
+			return false
+		}
+		for i, field := range t.fields {
+			setField(info, field, lines[i])
+		}
+		return info.Title != ""
+	}
+
+	matches := t.pattern.FindStringSubmatch(nowPlaying)
+	if matches == nil {
+		return false
+	}
+	for i, name := range t.pattern.SubexpNames() {
+		setField(info, name, strings.TrimSpace(matches[i]))
+	}
+
+	return info.Title != ""
+}
+
+func setField(info *models.TrackInfo, field, value string) {
+	switch field {
+	case "title":
+		info.Title = value
+	case "artist":
+		info.Artist = value
+	case "album":
+		info.Album = value
+	}
+}
+
+func splitLines(s string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+var (
+	dashes         = strings.NewReplacer(" – ", " - ", " — ", " - ")
+	fileExtension  = regexp.MustCompile(`(?i)\.(?:mp3|m4a|aac|ogg|flac|wav)$`)
+	repeatedSpaces = regexp.MustCompile(`[ \t]+`)
+)
+
+// cleanNowPlaying smooths over differences in how tracks were tagged:
+// typographic dashes, stray whitespace and file extensions left in titles
+func cleanNowPlaying(s string) string {
+	s = strings.Replace(s, "\r", "", -1)
+	s = dashes.Replace(s)
+	s = repeatedSpaces.ReplaceAllString(s, " ")
+	s = strings.TrimSpace(s)
+	return fileExtension.ReplaceAllString(s, "")
+}
@@ -0,0 +1,120 @@
+package dpark
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+func TestDefaultTemplates(t *testing.T) {
+	tests := []struct {
+		name       string
+		nowPlaying string
+		want       models.TrackInfo
+	}{
+		{
+			"album, title and artist",
+			"Magic Kingdom - Main Street Electrical Parade - Disneyland Band",
+			models.TrackInfo{Album: "Magic Kingdom", Title: "Main Street Electrical Parade", Artist: "Disneyland Band"},
+		},
+		{
+			"artist and title",
+			"Disneyland Band - Main Street Electrical Parade",
+			models.TrackInfo{Artist: "Disneyland Band", Title: "Main Street Electrical Parade"},
+		},
+		{
+			"title containing a dash",
+			"Epcot - Reflections of Earth - part 2 - Gavin Greenaway",
+			models.TrackInfo{Album: "Epcot", Title: "Reflections of Earth - part 2", Artist: "Gavin Greenaway"},
+		},
+		{
+			"newline separated",
+			"Disneyland Hotel\nLobby Loop 2019\nDisneyland Resort",
+			models.TrackInfo{Album: "Disneyland Hotel", Title: "Lobby Loop 2019", Artist: "Disneyland Resort"},
+		},
+		{
+			"newline separated with blank lines",
+			"\r\nDisneyland Band\r\n\r\nMain Street Electrical Parade\r\n",
+			models.TrackInfo{Artist: "Disneyland Band", Title: "Main Street Electrical Parade"},
+		},
+		{
+			"typographic dashes and a file extension",
+			"Magic Kingdom – Main Street Electrical Parade — Disneyland Band.mp3",
+			models.TrackInfo{Album: "Magic Kingdom", Title: "Main Street Electrical Parade", Artist: "Disneyland Band"},
+		},
+		{
+			"title alone",
+			"DPark Radio Station ID",
+			models.TrackInfo{Title: "DPark Radio Station ID"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload, err := json.Marshal(dParkResponse{NowPlaying: test.nowPlaying})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseTrackInfo(backgroundName, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Title != test.want.Title || got.Artist != test.want.Artist || got.Album != test.want.Album {
+				t.Errorf("got %q by %q on %q, want %q by %q on %q",
+					got.Title, got.Artist, got.Album, test.want.Title, test.want.Artist, test.want.Album)
+			}
+		})
+	}
+}
+
+func TestNoTemplateMatches(t *testing.T) {
+	SetTemplates(christmasName, ArtistTitle, AlbumTitleArtist)
+	defer delete(stationTemplates, christmasName)
+
+	got, err := parseTrackInfo(christmasName, []byte(`{"nowplaying":"Candlelight Processional"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Candlelight Processional" || got.Artist != "" || got.Album != "" {
+		t.Errorf("got %+v, want the whole string as the title", got)
+	}
+}
+
+func TestTemplateOrder(t *testing.T) {
+	SetTemplates(resortName, TitleArtist)
+	defer delete(stationTemplates, resortName)
+
+	got, err := parseTrackInfo(resortName, []byte(`{"nowplaying":"Lobby Loop 2019 - Disneyland Resort"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != "Lobby Loop 2019" || got.Artist != "Disneyland Resort" {
+		t.Errorf("got %q by %q, want %q by %q", got.Title, got.Artist, "Lobby Loop 2019", "Disneyland Resort")
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	named, err := ParseTemplate("title-artist")
+	if err != nil || named != TitleArtist {
+		t.Errorf("title-artist parsed into %v, %v, want the named template", named, err)
+	}
+
+	custom, err := ParseTemplate(`^(?P<title>.+) by (?P<artist>.+)$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &models.TrackInfo{}
+	if !custom.parse("Lobby Loop 2019 by Disneyland Resort", info) {
+		t.Fatal("custom template didn't match")
+	}
+	if info.Title != "Lobby Loop 2019" || info.Artist != "Disneyland Resort" {
+		t.Errorf("got %q by %q", info.Title, info.Artist)
+	}
+
+	for _, bad := range []string{`(`, `^(?P<artist>.+)$`, `^(?P<song>.+)$`} {
+		if _, err := ParseTemplate(bad); err == nil {
+			t.Errorf("ParseTemplate(%q) succeeded, want an error", bad)
+		}
+	}
+}
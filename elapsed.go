@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+const (
+	// plays shorter or longer than these are more likely a missed fetch or a
+	// stalled stream than a real track
+	minLearnedDuration = 10 * time.Second
+	maxLearnedDuration = 3 * time.Hour
+
+	progressBarWidth = 20
+)
+
+// learnedDuration is the average length of a track's full plays
+type learnedDuration struct {
+	Seconds float64 `json:"seconds"`
+	Plays   int     `json:"plays"`
+}
+
+// trackClock times tracks from stations which report no start time or
+// duration, learning each title's duration from plays heard from start to
+// end
+type trackClock struct {
+	path    string
+	learned map[string]learnedDuration
+
+	station   string
+	title     string
+	startedAt time.Time
+	sawStart  bool
+}
+
+// newTrackClock loads durations learned earlier from path. A missing or
+// unreadable file starts over with nothing learned.
+func newTrackClock(path string) *trackClock {
+	c := &trackClock{path: path, learned: map[string]learnedDuration{}}
+	if raw, err := ioutil.ReadFile(path); err == nil {
+		_ = json.Unmarshal(raw, &c.learned)
+	}
+	return c
+}
+
+// defaultDurationsPath is where learned durations are kept between runs
+func defaultDurationsPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "stream-player", "durations.json")
+}
+
+// observe fills in info's start time, and its duration when learned, for
+// tracks whose station doesn't report them. heardAt is when info started
+// being heard.
+func (c *trackClock) observe(station string, info *models.TrackInfo, heardAt time.Time) {
+	if c == nil || !info.StartedAt.IsZero() {
+		return
+	}
+
+	switch {
+	case station != c.station:
+		// tuned in part way through, so this play can't be learned from
+		c.station = station
+		c.title = info.Title
+		c.startedAt = heardAt
+		c.sawStart = false
+	case info.Title != c.title:
+		if c.sawStart {
+			c.learn(c.key(c.title), heardAt.Sub(c.startedAt))
+		}
+		c.title = info.Title
+		c.startedAt = heardAt
+		c.sawStart = true
+	}
+
+	info.StartedAt = c.startedAt
+	if learned, ok := c.learned[c.key(info.Title)]; ok && info.Duration == 0 {
+		info.Duration = learned.Seconds
+		info.DurationEstimated = true
+	}
+}
+
+func (c *trackClock) key(title string) string {
+	return c.station + "\n" + title
+}
+
+func (c *trackClock) learn(key string, played time.Duration) {
+	if played < minLearnedDuration || played > maxLearnedDuration {
+		return
+	}
+
+	learned := c.learned[key]
+	learned.Seconds = (learned.Seconds*float64(learned.Plays) + played.Seconds()) / float64(learned.Plays+1)
+	learned.Plays++
+	c.learned[key] = learned
+
+	c.save()
+}
+
+func (c *trackClock) save() {
+	if c.path == "" {
+		return
+	}
+
+	raw, err := json.Marshal(c.learned)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return
+	}
+	_ = ioutil.WriteFile(c.path, raw, 0644)
+}
+
+// writeProgress writes how far into info playback is: a progress bar for
+// estimated durations and the elapsed time for tracks with no duration
+func writeProgress(msg *strings.Builder, info *models.TrackInfo) {
+	if info.StartedAt.IsZero() {
+		return
+	}
+
+	elapsed := time.Since(info.StartedAt)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	switch {
+	case info.Duration > 0 && info.DurationEstimated:
+		progress := elapsed.Seconds() / info.Duration
+		if progress > 1 {
+			progress = 1
+		}
+		filled := int(math.Round(progress * progressBarWidth))
+		msg.WriteString(" [")
+		msg.WriteString(strings.Repeat("#", filled))
+		msg.WriteString(strings.Repeat("-", progressBarWidth-filled))
+		msg.WriteString("]")
+	case info.Duration == 0:
+		msg.WriteString(fmt.Sprintf(
+			" (%02.f:%02.f elapsed)",
+			math.Floor(elapsed.Minutes()),
+			math.Mod(math.Floor(elapsed.Seconds()), 60),
+		))
+	}
+}
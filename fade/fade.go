@@ -0,0 +1,74 @@
+package fade
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DefaultSteps is how many volume changes a crossfade makes
+const DefaultSteps = 30
+
+// Player is the part of a media player a crossfade controls
+type Player interface {
+	SetVolume(volume int) error
+}
+
+// Fader crossfades between two players
+type Fader struct {
+	Duration time.Duration
+	Steps    int
+	// After waits between steps like time.After and may be replaced, e.g. to
+	// step through a fade without waiting
+	After func(time.Duration) <-chan time.Time
+}
+
+// New builds a Fader which crossfades over duration in real time
+func New(duration time.Duration) *Fader {
+	return &Fader{
+		Duration: duration,
+		Steps:    DefaultSteps,
+		After:    time.After,
+	}
+}
+
+// Crossfade lowers out from its volume, from, to silence while raising in
+// from silence to its volume, to. The curves keep the combined power even so
+// the music doesn't dip in the middle. It stops early with ctx's error when
+// ctx is done, leaving both players where they were.
+func (f *Fader) Crossfade(ctx context.Context, out, in Player, from, to int) error {
+	steps := f.Steps
+	if steps < 1 {
+		steps = 1
+	}
+	interval := f.Duration / time.Duration(steps)
+
+	for step := 1; step <= steps; step++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-f.After(interval):
+		}
+
+		outVolume, inVolume := Levels(float64(step)/float64(steps), from, to)
+		if err := out.SetVolume(outVolume); err != nil {
+			return fmt.Errorf("failed to fade out: %w", err)
+		}
+		if err := in.SetVolume(inVolume); err != nil {
+			return fmt.Errorf("failed to fade in: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Levels is the volume of the outgoing and incoming players progress of the
+// way, from 0 to 1, through a crossfade
+func Levels(progress float64, from, to int) (out, in int) {
+	progress = math.Max(0, math.Min(1, progress))
+	angle := progress * math.Pi / 2
+	out = int(math.Round(float64(from) * math.Cos(angle)))
+	in = int(math.Round(float64(to) * math.Sin(angle)))
+	return out, in
+}
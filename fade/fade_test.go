@@ -0,0 +1,173 @@
+package fade
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePlayer records every volume it's set to
+type fakePlayer struct {
+	mu      sync.Mutex
+	volumes []int
+	err     error
+}
+
+func (p *fakePlayer) SetVolume(volume int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.volumes = append(p.volumes, volume)
+	return nil
+}
+
+func (p *fakePlayer) Volumes() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int{}, p.volumes...)
+}
+
+// manualClock steps a fade each time tick is called
+type manualClock struct {
+	waits chan time.Duration
+	ticks chan time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{waits: make(chan time.Duration), ticks: make(chan time.Time)}
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.ticks
+}
+
+// tick waits for the fade to ask for its next step and then lets it take
+// it, returning how long it asked to wait
+func (c *manualClock) tick(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.waits:
+		c.ticks <- time.Now()
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("fade didn't wait for another step")
+		return 0
+	}
+}
+
+func crossfade(ctx context.Context, f *Fader, out, in Player, from, to int) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- f.Crossfade(ctx, out, in, from, to)
+	}()
+	return done
+}
+
+func TestCrossfadeSteps(t *testing.T) {
+	clock := newManualClock()
+	f := &Fader{Duration: 4 * time.Second, Steps: 4, After: clock.After}
+	out, in := &fakePlayer{}, &fakePlayer{}
+
+	done := crossfade(context.Background(), f, out, in, 100, 80)
+	for i := 0; i < 4; i++ {
+		if d := clock.tick(t); d != time.Second {
+			t.Errorf("step %d waited %v, want 1s", i, d)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := out.Volumes(), []int{92, 71, 38, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("faded out through %v, want %v", got, want)
+	}
+	if got, want := in.Volumes(), []int{31, 57, 74, 80}; !reflect.DeepEqual(got, want) {
+		t.Errorf("faded in through %v, want %v", got, want)
+	}
+}
+
+func TestCrossfadeCancelledBeforeStarting(t *testing.T) {
+	f := &Fader{Duration: time.Second, Steps: 10, After: func(time.Duration) <-chan time.Time {
+		return nil
+	}}
+	out, in := &fakePlayer{}, &fakePlayer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.Crossfade(ctx, out, in, 100, 100); err != context.Canceled {
+		t.Errorf("error is %v, want %v", err, context.Canceled)
+	}
+	if len(out.Volumes()) > 0 || len(in.Volumes()) > 0 {
+		t.Errorf("volumes changed to %v and %v after cancelling", out.Volumes(), in.Volumes())
+	}
+}
+
+// Pausing cancels a fade part way through, which must leave both players
+// at the last step's volume for the caller to stop or release
+func TestCrossfadePausedMidFade(t *testing.T) {
+	clock := newManualClock()
+	f := &Fader{Duration: time.Second, Steps: 10, After: clock.After}
+	out, in := &fakePlayer{}, &fakePlayer{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := crossfade(ctx, f, out, in, 100, 100)
+	for i := 0; i < 3; i++ {
+		clock.tick(t)
+	}
+
+	// the fade is waiting on its fourth step
+	<-clock.waits
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("error is %v, want %v", err, context.Canceled)
+	}
+
+	if got := out.Volumes(); len(got) != 3 || got[2] != 89 {
+		t.Errorf("faded out through %v, want 3 steps ending at 89", got)
+	}
+	if got := in.Volumes(); len(got) != 3 || got[2] != 45 {
+		t.Errorf("faded in through %v, want 3 steps ending at 45", got)
+	}
+}
+
+func TestCrossfadePlayerError(t *testing.T) {
+	failure := errors.New("player released")
+	f := &Fader{Duration: time.Second, Steps: 2, After: func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}}
+	out, in := &fakePlayer{err: failure}, &fakePlayer{}
+
+	if err := f.Crossfade(context.Background(), out, in, 100, 100); !errors.Is(err, failure) {
+		t.Errorf("error is %v, want it to wrap %v", err, failure)
+	}
+	if len(in.Volumes()) > 0 {
+		t.Errorf("faded in to %v after fading out failed", in.Volumes())
+	}
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		progress float64
+		out, in  int
+	}{
+		{-1, 100, 0},
+		{0, 100, 0},
+		{0.5, 71, 71},
+		{1, 0, 100},
+		{2, 0, 100},
+	}
+	for _, test := range tests {
+		out, in := Levels(test.progress, 100, 100)
+		if out != test.out || in != test.in {
+			t.Errorf("Levels(%v) = %d, %d, want %d, %d", test.progress, out, in, test.out, test.in)
+		}
+	}
+}
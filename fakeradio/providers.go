@@ -0,0 +1,128 @@
+package fakeradio
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// live365TimeFormat is how Live365 sends times, with a space instead of a T
+const live365TimeFormat = "2006-01-02 15:04:05.000000-07:00"
+
+type samSong struct {
+	Title       string `json:"Title"`
+	Album       string `json:"Album"`
+	Artist      string `json:"Artist"`
+	Duration    string `json:"Duration"`
+	DatePlayed  string `json:"DatePlayed"`
+	MediaItemID string `json:"MediaItemId"`
+	MediaType   string `json:"MediaTypeCode"`
+}
+
+type live365Response struct {
+	CurrentTrack live365Song   `json:"current-track"`
+	LastPlayed   []live365Song `json:"last-played"`
+}
+
+type live365Song struct {
+	Title      string  `json:"title"`
+	Artist     string  `json:"artist"`
+	Duration   float64 `json:"duration"`
+	Start      string  `json:"start"`
+	End        string  `json:"end"`
+	Art        string  `json:"art"`
+	SyncOffset string  `json:"sync_offset"`
+}
+
+type radioBossResponse struct {
+	NowPlaying string `json:"nowplaying"`
+}
+
+// render encodes the station's tracks the way provider would
+func (st *station) render(provider Provider, query url.Values) ([]byte, string) {
+	var payload interface{}
+	switch provider {
+	case SAM:
+		payload = st.samHistory(query)
+	case Live365:
+		payload = st.live365()
+	case RadioBoss:
+		payload = radioBossResponse{NowPlaying: st.radioBoss()}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		body = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+	return body, "application/json; charset=utf-8"
+}
+
+// samHistory lists the station's tracks newest first, limited by the top
+// query parameter like SAM Cloud
+func (st *station) samHistory(query url.Values) []samSong {
+	recent := st.recent()
+	if top, err := strconv.Atoi(query.Get("top")); err == nil && top >= 0 && top < len(recent) {
+		recent = recent[:top]
+	}
+
+	songs := make([]samSong, len(recent))
+	for i, p := range recent {
+		mediaType := p.MediaType
+		if mediaType == "" {
+			mediaType = "MUS"
+		}
+		songs[i] = samSong{
+			Title:       p.Title,
+			Album:       p.Album,
+			Artist:      p.Artist,
+			Duration:    isoDuration(p.Duration),
+			DatePlayed:  fmt.Sprintf("/Date(%d+0000)/", p.startedAt.UnixNano()/int64(time.Millisecond)),
+			MediaItemID: strconv.Itoa(i + 1),
+			MediaType:   mediaType,
+		}
+	}
+	return songs
+}
+
+// live365 reports the current track and the ones before it
+func (st *station) live365() live365Response {
+	resp := live365Response{CurrentTrack: live365Track(st.current), LastPlayed: []live365Song{}}
+	for _, p := range st.history {
+		resp.LastPlayed = append(resp.LastPlayed, live365Track(p))
+	}
+	return resp
+}
+
+func live365Track(p played) live365Song {
+	return live365Song{
+		Title:      p.Title,
+		Artist:     p.Artist,
+		Duration:   p.Duration.Seconds(),
+		Start:      p.startedAt.UTC().Format(live365TimeFormat),
+		End:        p.startedAt.Add(p.Duration).UTC().Format(live365TimeFormat),
+		Art:        p.ArtURL,
+		SyncOffset: "0",
+	}
+}
+
+// radioBoss formats the current track like DPark Radio's RadioBoss does,
+// album - title - artist
+func (st *station) radioBoss() string {
+	switch {
+	case st.current.Artist == "":
+		return st.current.Title
+	case st.current.Album == "":
+		return st.current.Artist + " - " + st.current.Title
+	}
+	return st.current.Album + " - " + st.current.Title + " - " + st.current.Artist
+}
+
+// isoDuration formats d as an ISO-8601 duration like SAM Cloud, e.g.
+// PT3M4.5S
+func isoDuration(d time.Duration) string {
+	minutes := int(d / time.Minute)
+	seconds := (d % time.Minute).Seconds()
+	return fmt.Sprintf("PT%dM%sS", minutes, strconv.FormatFloat(seconds, 'f', -1, 64))
+}
@@ -0,0 +1,324 @@
+// Package fakeradio impersonates the servers stations are played from, so
+// the player can be exercised without reaching them. Its Server answers SAM
+// Cloud, Live365 and RadioBoss track info requests and streams looping
+// silent MP3 audio with ICY metadata, and can be scripted to change tracks,
+// fail, hang or send malformed responses.
+package fakeradio
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider is a kind of server stations are played from
+type Provider string
+
+// Providers the Server impersonates
+const (
+	// SAM answers webapi/station/{id}/history like SAM Cloud
+	SAM Provider = "sam"
+	// Live365 answers station/{id} like Live365's API
+	Live365 Provider = "live365"
+	// RadioBoss answers w/nowplayinginfo?u={id} like RadioBoss
+	RadioBoss Provider = "radioboss"
+	// Stream answers every other path with audio, identified by its sid
+	// query parameter or last path segment
+	Stream Provider = "stream"
+)
+
+// Failure is how a station's requests misbehave
+type Failure int
+
+// Failures a station can be scripted with
+const (
+	// Healthy answers normally
+	Healthy Failure = iota
+	// ServerError responds 500 Internal Server Error
+	ServerError
+	// Timeout never responds, until the client gives up
+	Timeout
+	// Malformed responds 200 OK with a truncated body
+	Malformed
+)
+
+// maxHistory is how many previously played tracks are kept per station
+const maxHistory = 10
+
+// Track is a track a station plays
+type Track struct {
+	Title    string
+	Artist   string
+	Album    string
+	Duration time.Duration
+	// MediaType is reported by SAM stations, "MUS" when empty
+	MediaType string
+	// ArtURL is reported by Live365 stations
+	ArtURL string
+}
+
+// played is a track along with when it started
+type played struct {
+	Track
+	startedAt time.Time
+}
+
+// station is the scripted state of one station of one provider
+type station struct {
+	current  played
+	history  []played
+	playlist []Track
+	next     int
+	failure  Failure
+	requests int
+}
+
+type stationKey struct {
+	provider Provider
+	id       string
+}
+
+// Server is an httptest.Server impersonating every Provider at once.
+// Stations are created with a default playlist the first time they're
+// requested or scripted.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	stations map[stationKey]*station
+	done     chan struct{}
+	closed   sync.Once
+}
+
+// New starts a Server, which must be closed when done
+func New() *Server {
+	s := &Server{
+		stations: map[stationKey]*station{},
+		done:     make(chan struct{}),
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Close stops the server, letting go of requests scripted to time out
+func (s *Server) Close() {
+	s.closed.Do(func() {
+		close(s.done)
+		s.Server.Close()
+	})
+}
+
+// Play changes a station's track now. The track it was playing moves to
+// its history.
+func (s *Server) Play(provider Provider, id string, track Track) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.station(provider, id, time.Now())
+	st.play(played{Track: track, startedAt: time.Now()})
+}
+
+// SetPlaylist starts a station playing tracks now, one after another,
+// looping once they're all played. A track without a Duration plays until
+// the station is scripted again.
+func (s *Server) SetPlaylist(provider Provider, id string, tracks ...Track) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.station(provider, id, time.Now())
+	st.playlist = append([]Track(nil), tracks...)
+	st.next = 0
+	if len(tracks) > 0 {
+		st.play(played{Track: st.nextTrack(), startedAt: time.Now()})
+	}
+}
+
+// Fail makes a station's requests misbehave until it's given Healthy
+func (s *Server) Fail(provider Provider, id string, failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.station(provider, id, time.Now()).failure = failure
+}
+
+// Requests counts the requests made for a station
+func (s *Server) Requests(provider Provider, id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.stations[stationKey{provider, id}]
+	if !ok {
+		return 0
+	}
+	return st.requests
+}
+
+// RewriteURL points rawURL at the server, keeping its path and query, so a
+// station's real URLs can be used as they are
+func (s *Server) RewriteURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	server, _ := url.Parse(s.URL)
+	u.Scheme = server.Scheme
+	u.Host = server.Host
+	u.User = nil
+	return u.String()
+}
+
+// Transport sends every request to the server instead of the host it was
+// made for. Use it in an http.Client to point stations at the server without
+// changing them.
+func (s *Server) Transport() http.RoundTripper {
+	return rewritingTransport{server: s}
+}
+
+type rewritingTransport struct {
+	server *Server
+}
+
+func (t rewritingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rewritten, err := url.Parse(t.server.RewriteURL(req.URL.String()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL = rewritten
+	req.Host = ""
+	return t.server.Client().Transport.RoundTrip(req)
+}
+
+// ServeHTTP routes requests to the provider they're for
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	provider, id := route(r)
+
+	s.mu.Lock()
+	now := time.Now()
+	st := s.station(provider, id, now)
+	st.requests++
+	failure := st.failure
+	s.mu.Unlock()
+
+	switch failure {
+	case ServerError:
+		http.Error(w, "fakeradio: scripted server error", http.StatusInternalServerError)
+		return
+	case Timeout:
+		select {
+		case <-r.Context().Done():
+		case <-s.done:
+		}
+		return
+	}
+
+	if provider == Stream {
+		s.serveStream(w, r, id, failure == Malformed)
+		return
+	}
+
+	s.mu.Lock()
+	body, contentType := st.render(provider, r.URL.Query())
+	s.mu.Unlock()
+
+	if failure == Malformed {
+		body = body[:len(body)/2]
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(body)
+}
+
+// route finds the provider and station a request is for
+func route(r *http.Request) (Provider, string) {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/webapi/station/") && strings.HasSuffix(path, "/history"):
+		return SAM, strings.TrimSuffix(strings.TrimPrefix(path, "/webapi/station/"), "/history")
+	case strings.HasPrefix(path, "/station/"):
+		return Live365, strings.Trim(strings.TrimPrefix(path, "/station/"), "/")
+	case path == "/w/nowplayinginfo":
+		return RadioBoss, r.URL.Query().Get("u")
+	}
+
+	if sid := r.URL.Query().Get("sid"); sid != "" {
+		return Stream, sid
+	}
+	segments := strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == ';' })
+	if len(segments) == 0 {
+		return Stream, ""
+	}
+	return Stream, segments[len(segments)-1]
+}
+
+// station finds or creates a station, bringing its playlist up to now.
+// s.mu must be held.
+func (s *Server) station(provider Provider, id string, now time.Time) *station {
+	key := stationKey{provider, id}
+	st, ok := s.stations[key]
+	if !ok {
+		st = &station{playlist: defaultPlaylist(id)}
+		st.current = played{Track: st.nextTrack(), startedAt: now}
+		s.stations[key] = st
+	}
+	st.advance(now)
+	return st
+}
+
+// defaultPlaylist is a few three minute tracks named for the station
+func defaultPlaylist(id string) []Track {
+	tracks := make([]Track, 3)
+	for i := range tracks {
+		tracks[i] = Track{
+			Title:    fmt.Sprintf("Track %d", i+1),
+			Artist:   "Fake Radio",
+			Album:    fmt.Sprintf("Station %s", id),
+			Duration: 3 * time.Minute,
+		}
+	}
+	return tracks
+}
+
+// play makes p the current track, moving the previous one to the history
+func (st *station) play(p played) {
+	st.history = append([]played{st.current}, st.history...)
+	if len(st.history) > maxHistory {
+		st.history = st.history[:maxHistory]
+	}
+	st.current = p
+}
+
+// nextTrack takes the next track from the playlist
+func (st *station) nextTrack() Track {
+	track := st.playlist[st.next]
+	st.next = (st.next + 1) % len(st.playlist)
+	return track
+}
+
+// advance plays through the playlist until the current track is still
+// playing at now
+func (st *station) advance(now time.Time) {
+	for len(st.playlist) > 0 && st.current.Duration > 0 {
+		endsAt := st.current.startedAt.Add(st.current.Duration)
+		if endsAt.After(now) {
+			return
+		}
+		st.play(played{Track: st.nextTrack(), startedAt: endsAt})
+	}
+}
+
+// recent lists the current track followed by the history
+func (st *station) recent() []played {
+	return append([]played{st.current}, st.history...)
+}
+
+// streamTitle is the current track as sent in ICY metadata
+func (st *station) streamTitle() string {
+	if st.current.Artist == "" {
+		return st.current.Title
+	}
+	return st.current.Artist + " - " + st.current.Title
+}
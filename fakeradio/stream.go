@@ -0,0 +1,82 @@
+package fakeradio
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codegoalie/stream-player/relay"
+)
+
+const (
+	// frameSize is the length of a 128kbps 44.1kHz mono MPEG-1 Layer III
+	// frame without padding
+	frameSize = 417
+	// frameDuration is how long each frame plays, 1152 samples at 44.1kHz
+	frameDuration = 1152 * time.Second / 44100
+	// burst is how much audio is sent straight away, like stations do to
+	// fill players' buffers
+	burst = 2 * time.Second
+	// tick is how often more audio is sent
+	tick = 100 * time.Millisecond
+)
+
+// silentFrame is an MP3 frame whose side info and main data are zeroed, so
+// it decodes to silence
+var silentFrame = func() []byte {
+	frame := make([]byte, frameSize)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0xC0})
+	return frame
+}()
+
+// serveStream sends silent MP3 audio in real time, with the station's track
+// in ICY metadata when asked for, until the client goes away. A malformed
+// stream sends noise instead of MP3 frames.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request, id string, malformed bool) {
+	w.Header().Set("Content-Type", "audio/mpeg")
+	w.Header().Set("icy-name", "Fake Radio "+id)
+
+	var out io.Writer = w
+	if strings.TrimSpace(r.Header.Get("Icy-MetaData")) == "1" {
+		w.Header().Set("icy-metaint", strconv.Itoa(relay.DefaultMetaInt))
+		out = relay.NewICYWriter(w, relay.DefaultMetaInt, func() string {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.station(Stream, id, time.Now()).streamTitle()
+		})
+	}
+	w.WriteHeader(http.StatusOK)
+
+	frame := silentFrame
+	if malformed {
+		frame = make([]byte, frameSize)
+		rand.Read(frame)
+	}
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	startedAt := time.Now()
+	sent := 0
+	for {
+		due := int((time.Since(startedAt) + burst) / frameDuration)
+		for ; sent < due; sent++ {
+			if _, err := out.Write(frame); err != nil {
+				return
+			}
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/parks"
+)
+
+const (
+	followRefreshInterval = 30 * time.Second
+	followFetchWorkers    = 4
+	followFetchTimeout    = 5 * time.Second
+)
+
+// parkFollower switches stations to keep playing music from one park
+type parkFollower struct {
+	park     parks.Park
+	stations []nowPlaying
+	current  *models.TrackInfo
+}
+
+func newParkFollower(park parks.Park) *parkFollower {
+	return &parkFollower{park: park}
+}
+
+// refreshNowPlaying periodically fetches every station's current track and
+// sends the results on updates
+func refreshNowPlaying(updates chan<- []nowPlaying) {
+	for {
+		updates <- fetchAllNowPlaying(medias, followFetchWorkers, followFetchTimeout)
+		time.Sleep(followRefreshInterval)
+	}
+}
+
+// trackChanged records the track now playing on current and returns the index
+// of the station to switch to or -1 to stay
+func (f *parkFollower) trackChanged(info *models.TrackInfo, current int) int {
+	if f == nil {
+		return -1
+	}
+
+	f.current = info
+	return f.next(current)
+}
+
+// update replaces the cached tracks of all stations and returns the index of
+// the station to switch to or -1 to stay
+func (f *parkFollower) update(stations []nowPlaying, current int) int {
+	if f == nil {
+		return -1
+	}
+
+	f.stations = stations
+	return f.next(current)
+}
+
+func (f *parkFollower) next(current int) int {
+	if f.current == nil || parks.Classify(f.current).Park == f.park {
+		return -1
+	}
+
+	best := -1
+	for i, station := range f.stations {
+		if i == current || station.info == nil || i >= len(medias) {
+			continue
+		}
+		if parks.Classify(station.info).Park != f.park {
+			continue
+		}
+		if best < 0 || station.Remaining > f.stations[best].Remaining {
+			best = i
+		}
+	}
+
+	if best >= 0 {
+		// wait for the new station's track before judging it
+		f.current = nil
+	}
+
+	return best
+}
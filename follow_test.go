@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/parks"
+)
+
+var (
+	epcotTrack = &models.TrackInfo{Title: "World Showcase Entrance Loop", Album: "EPCOT"}
+	mkTrack    = &models.TrackInfo{Title: "Magic Kingdom Caribbean Plaza Area Loop pt1", Artist: "Magic Kingdom"}
+	filmTrack  = &models.TrackInfo{Title: "Let It Go", Artist: "Idina Menzel"}
+)
+
+func TestParkFollowerSwitches(t *testing.T) {
+	cases := []struct {
+		name     string
+		stations []nowPlaying
+		current  int
+		playing  *models.TrackInfo
+		want     int
+	}{
+		{
+			name:     "stays in the park",
+			stations: []nowPlaying{{info: epcotTrack}, {info: epcotTrack}},
+			playing:  epcotTrack,
+			want:     -1,
+		},
+		{
+			name:     "leaves for the park",
+			stations: []nowPlaying{{info: filmTrack}, {info: mkTrack}, {info: epcotTrack}},
+			playing:  filmTrack,
+			want:     2,
+		},
+		{
+			name:     "picks the longest remaining",
+			stations: []nowPlaying{{info: filmTrack}, {info: epcotTrack, Remaining: 30}, {info: epcotTrack, Remaining: 240}, {info: epcotTrack, Remaining: 60}},
+			playing:  filmTrack,
+			want:     2,
+		},
+		{
+			name:     "ignores the current station's stale track",
+			stations: []nowPlaying{{info: epcotTrack}, {info: filmTrack}},
+			playing:  mkTrack,
+			want:     -1,
+		},
+		{
+			name:     "ignores stations which failed",
+			stations: []nowPlaying{{info: filmTrack}, {Error: "timed out"}},
+			playing:  filmTrack,
+			want:     -1,
+		},
+		{
+			name:     "stays when no station plays the park",
+			stations: []nowPlaying{{info: filmTrack}, {info: mkTrack}},
+			playing:  filmTrack,
+			want:     -1,
+		},
+		{
+			name:     "waits for the first track",
+			stations: []nowPlaying{{info: filmTrack}, {info: epcotTrack}},
+			want:     -1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := newParkFollower(parks.EPCOT)
+			f.update(c.stations, c.current)
+			if got := f.trackChanged(c.playing, c.current); got != c.want {
+				t.Errorf("switched to %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParkFollowerWaitsForNewStation(t *testing.T) {
+	f := newParkFollower(parks.EPCOT)
+	if index := f.trackChanged(filmTrack, 0); index != -1 {
+		t.Fatalf("switched to %d before any station was fetched", index)
+	}
+
+	stations := []nowPlaying{{info: filmTrack}, {info: epcotTrack}}
+	if index := f.update(stations, 0); index != 1 {
+		t.Fatalf("switched to %d when stations were fetched, want 1", index)
+	}
+	// the cached tracks are old by the time the new station plays, so they
+	// don't switch again until its track is known
+	if index := f.update(stations, 1); index != -1 {
+		t.Errorf("switched to %d before the new station's track was known", index)
+	}
+	if index := f.trackChanged(epcotTrack, 1); index != -1 {
+		t.Errorf("switched to %d away from the park", index)
+	}
+
+	// the station moves on from the park, so follow it elsewhere
+	stations = []nowPlaying{{info: epcotTrack}, {info: filmTrack}}
+	f.update(stations, 1)
+	if index := f.trackChanged(filmTrack, 1); index != 0 {
+		t.Errorf("switched to %d when the station left the park, want 0", index)
+	}
+}
+
+func TestParkFollowerDisabled(t *testing.T) {
+	var f *parkFollower
+	if index := f.trackChanged(filmTrack, 0); index != -1 {
+		t.Errorf("a nil follower switched to %d", index)
+	}
+	if index := f.update([]nowPlaying{{info: epcotTrack}}, 1); index != -1 {
+		t.Errorf("a nil follower switched to %d", index)
+	}
+}
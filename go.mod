@@ -3,7 +3,7 @@ module github.com/codegoalie/stream-player
 go 1.13
 
 require (
-	github.com/adrg/libvlc-go/v3 v3.0.8
+	github.com/adrg/libvlc-go/v3 v3.1.0
 	github.com/codegoalie/golibnotify v0.1.0
 	github.com/godbus/dbus/v5 v5.0.3
 	github.com/gosuri/uilive v0.0.4
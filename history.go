@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// trackHistory keeps the recently played tracks of the current station,
+// remembering them locally for stations whose metadata doesn't include any
+type trackHistory struct {
+	size    int
+	station string
+	tracks  []*models.TrackInfo
+}
+
+func newTrackHistory(size int) *trackHistory {
+	return &trackHistory{size: size}
+}
+
+// update records info as now playing on station. recent is the history
+// reported by the station, if any, and replaces what was kept locally.
+func (h *trackHistory) update(station string, info *models.TrackInfo, recent []*models.TrackInfo) {
+	if h.station != station {
+		h.station = station
+		h.tracks = nil
+	}
+
+	if recent != nil {
+		h.tracks = recent
+	} else if len(h.tracks) == 0 || h.tracks[0].Title != info.Title {
+		seen := *info
+		if seen.StartedAt.IsZero() {
+			seen.StartedAt = time.Now()
+		}
+		h.tracks = append([]*models.TrackInfo{&seen}, h.tracks...)
+	}
+
+	keep := 1
+	if h.size > 0 {
+		keep = h.size + 1
+	}
+	if len(h.tracks) > keep {
+		h.tracks = h.tracks[:keep]
+	}
+}
+
+// previous lists the tracks played before the current one, most recent first
+func (h *trackHistory) previous() []*models.TrackInfo {
+	if h.size <= 0 || len(h.tracks) < 2 {
+		return nil
+	}
+
+	end := len(h.tracks)
+	if end > h.size+1 {
+		end = h.size + 1
+	}
+	return h.tracks[1:end]
+}
+
+// writeTo writes a line for each previous track with its start time
+func (h *trackHistory) writeTo(msg *strings.Builder) {
+	for _, track := range h.previous() {
+		msg.WriteString("  ")
+		if !track.StartedAt.IsZero() {
+			msg.WriteString(track.StartedAt.Local().Format("15:04"))
+			msg.WriteString("  ")
+		}
+		msg.WriteString(track.Title)
+		if track.Artist != "" {
+			msg.WriteString(" - ")
+			msg.WriteString(track.Artist)
+		}
+		msg.WriteString("\n")
+	}
+}
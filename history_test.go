@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+func TestTrackHistory(t *testing.T) {
+	history := newTrackHistory(2)
+	for _, title := range []string{"one", "two", "two", "three", "four"} {
+		history.update("station", &models.TrackInfo{Title: title}, nil)
+	}
+
+	previous := history.previous()
+	if len(previous) != 2 || previous[0].Title != "three" || previous[1].Title != "two" {
+		t.Errorf("got %v, want three then two", titles(previous))
+	}
+
+	history.update("other", &models.TrackInfo{Title: "five"}, nil)
+	if previous := history.previous(); len(previous) != 0 {
+		t.Errorf("switching stations kept %v", titles(previous))
+	}
+}
+
+func TestTrackHistoryWithoutSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		history := newTrackHistory(size)
+		history.update("station", &models.TrackInfo{Title: "one"}, nil)
+		history.update("station", &models.TrackInfo{Title: "two"}, []*models.TrackInfo{
+			{Title: "two"}, {Title: "one"},
+		})
+		if previous := history.previous(); len(previous) != 0 {
+			t.Errorf("size %d: got %v, want nothing", size, titles(previous))
+		}
+	}
+}
+
+func titles(tracks []*models.TrackInfo) []string {
+	var titles []string
+	for _, track := range tracks {
+		titles = append(titles, track.Title)
+	}
+	return titles
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codegoalie/stream-player/hls"
+)
+
+// hlsFlags adds a station for each -hls value of the form name=url, where
+// url is the station's HLS master playlist
+type hlsFlags []*hls.Station
+
+func (f *hlsFlags) String() string {
+	parts := []string{}
+	for _, station := range *f {
+		parts = append(parts, station.Name())
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *hlsFlags) Set(value string) error {
+	split := strings.SplitN(value, "=", 2)
+	if len(split) != 2 || strings.TrimSpace(split[0]) == "" {
+		return fmt.Errorf("expected name=url, got %q", value)
+	}
+
+	*f = append(*f, hls.NewStation(strings.TrimSpace(split[0]), strings.TrimSpace(split[1]), hls.BestQuality))
+	return nil
+}
+
+// apply sets which variant every HLS station prefers and adds them after
+// the built in stations
+func (f hlsFlags) apply(preference hls.Preference) {
+	for _, station := range f {
+		station.SetPreference(preference)
+		medias = append(medias, station)
+	}
+}
@@ -0,0 +1,127 @@
+package hls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+const id3HeaderSize = 10
+
+// ParseID3 reads the title, artist and album from the ID3v2 tag which
+// starts an audio segment. Packed audio segments carry their timed metadata
+// this way.
+func ParseID3(raw []byte) (*models.TrackInfo, error) {
+	size, err := id3Size(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < size {
+		return nil, fmt.Errorf("ID3 tag is truncated: %d of %d bytes", len(raw), size)
+	}
+
+	version := raw[3]
+	flags := raw[5]
+	frames := raw[id3HeaderSize:size]
+	if flags&0x40 != 0 && len(frames) >= 4 {
+		// skip the extended header
+		extended := int(binary.BigEndian.Uint32(frames))
+		if version == 4 {
+			extended = synchsafe(frames[:4])
+		} else {
+			extended += 4
+		}
+		if extended > len(frames) {
+			return nil, fmt.Errorf("ID3 extended header is truncated")
+		}
+		frames = frames[extended:]
+	}
+
+	info := &models.TrackInfo{}
+	for len(frames) >= id3HeaderSize && frames[0] != 0 {
+		id := string(frames[:4])
+		frameSize := int(binary.BigEndian.Uint32(frames[4:8]))
+		if version == 4 {
+			frameSize = synchsafe(frames[4:8])
+		}
+		if frameSize < 0 || id3HeaderSize+frameSize > len(frames) {
+			return info, fmt.Errorf("ID3 frame %s is truncated", id)
+		}
+		body := frames[id3HeaderSize : id3HeaderSize+frameSize]
+		frames = frames[id3HeaderSize+frameSize:]
+
+		switch id {
+		case "TIT2":
+			info.Title = decodeText(body)
+		case "TPE1":
+			info.Artist = decodeText(body)
+		case "TALB":
+			info.Album = decodeText(body)
+		}
+	}
+
+	return info, nil
+}
+
+// id3Size is the length of the ID3v2 tag at the start of raw, including its
+// header
+func id3Size(raw []byte) (int, error) {
+	if len(raw) < id3HeaderSize || string(raw[:3]) != "ID3" {
+		return 0, fmt.Errorf("no ID3 tag")
+	}
+	if raw[3] < 3 || raw[3] > 4 {
+		return 0, fmt.Errorf("unsupported ID3 version 2.%d", raw[3])
+	}
+	return id3HeaderSize + synchsafe(raw[6:10]), nil
+}
+
+// synchsafe decodes a 28 bit integer stored 7 bits to a byte
+func synchsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// decodeText decodes a text frame's body, which starts with its encoding
+func decodeText(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	text := body[1:]
+	var decoded string
+	switch body[0] {
+	case 1, 2:
+		bigEndian := body[0] == 2
+		if len(text) >= 2 {
+			switch {
+			case text[0] == 0xff && text[1] == 0xfe:
+				bigEndian, text = false, text[2:]
+			case text[0] == 0xfe && text[1] == 0xff:
+				bigEndian, text = true, text[2:]
+			}
+		}
+		units := make([]uint16, len(text)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = binary.BigEndian.Uint16(text[2*i:])
+			} else {
+				units[i] = binary.LittleEndian.Uint16(text[2*i:])
+			}
+		}
+		decoded = string(utf16.Decode(units))
+	case 3:
+		decoded = string(text)
+	default:
+		// ISO-8859-1 maps directly onto the first 256 code points
+		runes := make([]rune, len(text))
+		for i, b := range text {
+			runes[i] = rune(b)
+		}
+		decoded = string(runes)
+	}
+
+	// strings may be terminated, and 2.4 allows several separated by nulls
+	return strings.TrimRight(strings.SplitN(decoded, "\x00", 2)[0], " ")
+}
@@ -0,0 +1,198 @@
+// Package hls plays stations streamed over HTTP Live Streaming, choosing
+// between the variants of a master playlist and reading the track info sent
+// along with the audio.
+package hls
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variant is one encoding of a stream listed in a master playlist
+type Variant struct {
+	URI string
+	// Bandwidth is the peak bits per second, or the average when that's all
+	// the playlist gives
+	Bandwidth int
+	Codecs    string
+}
+
+// Segment is a chunk of audio listed in a media playlist along with any
+// track info sent with it
+type Segment struct {
+	URI      string
+	Duration float64
+	// Title is the text after the duration in its EXTINF tag
+	Title string
+	// ProgramDateTime is when the segment was recorded, if known
+	ProgramDateTime time.Time
+	// DateRange holds the attributes of an EXT-X-DATERANGE tag just before
+	// the segment
+	DateRange map[string]string
+}
+
+// MediaPlaylist lists the segments of a single variant
+type MediaPlaylist struct {
+	TargetDuration float64
+	MediaSequence  int
+	Segments       []Segment
+	// Ended is set when no more segments will be added
+	Ended bool
+}
+
+// ParseMaster parses a master playlist fetched from base, resolving variant
+// URIs against it. A media playlist is returned as its own single variant.
+func ParseMaster(raw []byte, base string) ([]Variant, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		err = fmt.Errorf("failed to parse master playlist url: %w", err)
+		return nil, err
+	}
+
+	lines, err := playlistLines(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var variants []Variant
+	var pending *Variant
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			attrs := parseAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+			bandwidth, _ := strconv.Atoi(attrs["BANDWIDTH"])
+			if bandwidth == 0 {
+				bandwidth, _ = strconv.Atoi(attrs["AVERAGE-BANDWIDTH"])
+			}
+			pending = &Variant{Bandwidth: bandwidth, Codecs: attrs["CODECS"]}
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// segments mean this is already a media playlist
+			return []Variant{{URI: base}}, nil
+		case strings.HasPrefix(line, "#"):
+		case pending != nil:
+			pending.URI = resolve(baseURL, line)
+			variants = append(variants, *pending)
+			pending = nil
+		}
+	}
+
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("master playlist lists no variants")
+	}
+	return variants, nil
+}
+
+// ParseMedia parses a media playlist fetched from base, resolving segment
+// URIs against it
+func ParseMedia(raw []byte, base string) (*MediaPlaylist, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		err = fmt.Errorf("failed to parse media playlist url: %w", err)
+		return nil, err
+	}
+
+	lines, err := playlistLines(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := &MediaPlaylist{}
+	var pending Segment
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-TARGETDURATION:"):
+			playlist.TargetDuration, _ = strconv.ParseFloat(strings.TrimPrefix(line, "#EXT-X-TARGETDURATION:"), 64)
+		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
+			playlist.MediaSequence, _ = strconv.Atoi(strings.TrimPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"))
+		case strings.HasPrefix(line, "#EXT-X-ENDLIST"):
+			playlist.Ended = true
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			pending.ProgramDateTime, _ = time.Parse(time.RFC3339Nano, strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"))
+		case strings.HasPrefix(line, "#EXT-X-DATERANGE:"):
+			pending.DateRange = parseAttributes(strings.TrimPrefix(line, "#EXT-X-DATERANGE:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)
+			pending.Duration, err = strconv.ParseFloat(strings.TrimSpace(info[0]), 64)
+			if err != nil {
+				err = fmt.Errorf("failed to parse segment duration: %w", err)
+				return nil, err
+			}
+			if len(info) > 1 {
+				pending.Title = strings.TrimSpace(info[1])
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			pending.URI = resolve(baseURL, line)
+			playlist.Segments = append(playlist.Segments, pending)
+			pending = Segment{}
+		}
+	}
+
+	return playlist, nil
+}
+
+// playlistLines splits an M3U8 playlist into its non-blank lines
+func playlistLines(raw []byte) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		err = fmt.Errorf("failed to read playlist: %w", err)
+		return nil, err
+	}
+
+	if len(lines) == 0 || lines[0] != "#EXTM3U" {
+		return nil, fmt.Errorf("not an M3U8 playlist")
+	}
+	return lines, nil
+}
+
+// parseAttributes parses an attribute list like
+// BANDWIDTH=64000,CODECS="mp4a.40.2" where quoted values may contain commas
+func parseAttributes(list string) map[string]string {
+	attrs := map[string]string{}
+	for list != "" {
+		eq := strings.IndexByte(list, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.TrimSpace(list[:eq])
+		list = list[eq+1:]
+
+		var value string
+		if strings.HasPrefix(list, `"`) {
+			end := strings.IndexByte(list[1:], '"')
+			if end < 0 {
+				value, list = list[1:], ""
+			} else {
+				value, list = list[1:end+1], list[end+2:]
+			}
+		} else if comma := strings.IndexByte(list, ','); comma >= 0 {
+			value, list = list[:comma], list[comma:]
+		} else {
+			value, list = list, ""
+		}
+		attrs[key] = value
+
+		list = strings.TrimPrefix(strings.TrimSpace(list), ",")
+	}
+	return attrs
+}
+
+func resolve(base *url.URL, ref string) string {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(refURL).String()
+}
@@ -0,0 +1,304 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/utils"
+)
+
+// masterTimeout limits fetching the master playlist in the background,
+// which has no context of its own
+const masterTimeout = 10 * time.Second
+
+// Preference picks between the variants of a master playlist
+type Preference string
+
+const (
+	// BestQuality prefers the variant with the highest bandwidth
+	BestQuality Preference = "best"
+	// LowData prefers the variant with the lowest bandwidth
+	LowData Preference = "low"
+)
+
+// ParsePreference parses a Preference by name
+func ParsePreference(s string) (Preference, error) {
+	switch Preference(strings.ToLower(strings.TrimSpace(s))) {
+	case BestQuality, "":
+		return BestQuality, nil
+	case LowData:
+		return LowData, nil
+	}
+
+	return "", fmt.Errorf("unknown HLS quality %q, expected best or low", s)
+}
+
+// Order sorts variants by preference, most preferred first
+func Order(variants []Variant, preference Preference) []Variant {
+	ordered := append([]Variant(nil), variants...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if preference == LowData {
+			return ordered[i].Bandwidth < ordered[j].Bandwidth
+		}
+		return ordered[i].Bandwidth > ordered[j].Bandwidth
+	})
+	return ordered
+}
+
+// Station is a station streamed over HLS. It plays the variant of its
+// master playlist matching its Preference and falls back to the next one
+// when a variant fails.
+type Station struct {
+	name      string
+	masterURL string
+
+	mu         sync.Mutex
+	preference Preference
+	variants   []Variant
+	current    int
+	// loading is set while the master playlist is fetched in the background
+	loading bool
+}
+
+// NewStation creates a station named name from the master playlist at
+// masterURL
+func NewStation(name, masterURL string, preference Preference) *Station {
+	return &Station{name: name, masterURL: masterURL, preference: preference}
+}
+
+// Name is the userpresentable name of the stream
+func (s *Station) Name() string {
+	return s.name
+}
+
+// SetPreference changes which variant is preferred, starting over from the
+// most preferred one
+func (s *Station) SetPreference(preference Preference) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preference = preference
+	s.variants = Order(s.variants, preference)
+	s.current = 0
+}
+
+// StreamURL provides the media playlist of the variant to play. Until the
+// master playlist has been fetched it's the master playlist itself, which
+// is fetched in the background rather than holding up the caller.
+func (s *Station) StreamURL() string {
+	s.prefetch()
+	return s.variantURL()
+}
+
+// StreamURLs lists every variant's media playlist to fall back to, starting
+// with StreamURL. It's just StreamURL until the master playlist has been
+// fetched.
+func (s *Station) StreamURLs() []string {
+	s.prefetch()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.variants) == 0 {
+		return []string{s.masterURL}
+	}
+	urls := []string{}
+	for i := 0; i < len(s.variants); i++ {
+		urls = append(urls, s.variants[(s.current+i)%len(s.variants)].URI)
+	}
+	return urls
+}
+
+// InfoURL is the media playlist of the variant being played
+func (s *Station) InfoURL() string {
+	return s.variantURL()
+}
+
+// ParseTrackInfo reads the track info in a media playlist fetched from
+// InfoURL
+func (s *Station) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
+	playlist, err := ParseMedia(raw, s.InfoURL())
+	if err != nil {
+		return nil, err
+	}
+
+	if info := playlistTrackInfo(playlist); info != nil {
+		return info, nil
+	}
+	return &models.TrackInfo{}, nil
+}
+
+// FetchTrackInfo fetches the playing variant's media playlist and reads its
+// track info, from the playlist's tags when it has any or else from the ID3
+// tag of its newest segment. A variant which can't be fetched is given up on
+// for the next one.
+func (s *Station) FetchTrackInfo(ctx context.Context) (*models.TrackInfo, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	variantURL := s.variantURL()
+	buf, err := utils.DefaultClient.Get(ctx, variantURL)
+	if err != nil {
+		s.fail(variantURL)
+		return nil, err
+	}
+
+	playlist, err := ParseMedia(buf.Bytes(), variantURL)
+	if err != nil {
+		s.fail(variantURL)
+		return nil, err
+	}
+
+	if info := playlistTrackInfo(playlist); info != nil {
+		return info, nil
+	}
+	if len(playlist.Segments) == 0 {
+		return &models.TrackInfo{}, nil
+	}
+
+	segment, err := utils.DefaultClient.Get(ctx, playlist.Segments[len(playlist.Segments)-1].URI)
+	if err != nil {
+		return nil, err
+	}
+	info, err := ParseID3(segment.Bytes())
+	if err != nil {
+		err = fmt.Errorf("failed to read segment track info: %w", err)
+		return nil, err
+	}
+	return info, nil
+}
+
+// prefetch starts fetching the master playlist in the background unless
+// it's been fetched or is being fetched already
+func (s *Station) prefetch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.variants != nil || s.loading {
+		return
+	}
+	s.loading = true
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), masterTimeout)
+		defer cancel()
+		_ = s.load(ctx)
+
+		s.mu.Lock()
+		s.loading = false
+		s.mu.Unlock()
+	}()
+}
+
+// load fetches the master playlist, once it's been fetched successfully
+func (s *Station) load(ctx context.Context) error {
+	s.mu.Lock()
+	loaded := s.variants != nil
+	s.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	buf, err := utils.DefaultClient.Get(ctx, s.masterURL)
+	if err != nil {
+		return err
+	}
+	variants, err := ParseMaster(buf.Bytes(), s.masterURL)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.variants = Order(variants, s.preference)
+	s.current = 0
+	return nil
+}
+
+func (s *Station) variantURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.variants) == 0 {
+		return s.masterURL
+	}
+	return s.variants[s.current].URI
+}
+
+// fail moves on from variantURL to the next variant, if it's still the one
+// being played
+func (s *Station) fail(variantURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.variants) > 0 && s.variants[s.current].URI == variantURL {
+		s.current = (s.current + 1) % len(s.variants)
+	}
+}
+
+// playlistTrackInfo reads the track of the newest segment which has track
+// info in its tags, or nil when none do
+func playlistTrackInfo(playlist *MediaPlaylist) *models.TrackInfo {
+	for i := len(playlist.Segments) - 1; i >= 0; i-- {
+		segment := playlist.Segments[i]
+		if info := dateRangeTrackInfo(segment.DateRange); info != nil {
+			return info
+		}
+
+		info := extinfTrackInfo(segment.Title)
+		if info == nil {
+			continue
+		}
+
+		// the track started with the first of its run of segments
+		first := i
+		for first > 0 && playlist.Segments[first-1].Title == segment.Title {
+			first--
+		}
+		if first > 0 {
+			info.StartedAt = playlist.Segments[first].ProgramDateTime
+		}
+		return info
+	}
+
+	return nil
+}
+
+// dateRangeTrackInfo reads a track from the X-TITLE, X-ARTIST and X-ALBUM
+// attributes of an EXT-X-DATERANGE tag
+func dateRangeTrackInfo(attrs map[string]string) *models.TrackInfo {
+	if attrs["X-TITLE"] == "" {
+		return nil
+	}
+
+	info := &models.TrackInfo{
+		Title:  attrs["X-TITLE"],
+		Artist: attrs["X-ARTIST"],
+		Album:  attrs["X-ALBUM"],
+	}
+	info.StartedAt, _ = time.Parse(time.RFC3339Nano, attrs["START-DATE"])
+	fmt.Sscanf(attrs["DURATION"], "%g", &info.Duration)
+	return info
+}
+
+// extinfTrackInfo reads a track from an EXTINF title, which is either a list
+// like title="Song",artist="Band" or plain text like "Band - Song"
+func extinfTrackInfo(title string) *models.TrackInfo {
+	if title == "" {
+		return nil
+	}
+
+	if strings.Contains(title, `="`) {
+		attrs := parseAttributes(title)
+		if attrs["title"] == "" {
+			return nil
+		}
+		return &models.TrackInfo{Title: attrs["title"], Artist: attrs["artist"], Album: attrs["album"]}
+	}
+
+	if split := strings.SplitN(title, " - ", 2); len(split) == 2 {
+		return &models.TrackInfo{Title: split[1], Artist: split[0]}
+	}
+	return &models.TrackInfo{Title: title}
+}
@@ -0,0 +1,52 @@
+package hls
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamURLDoesntWaitForMaster(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, "#EXTM3U\n"+
+			"#EXT-X-STREAM-INF:BANDWIDTH=64000,CODECS=\"mp4a.40.5\"\nlow/index.m3u8\n"+
+			"#EXT-X-STREAM-INF:BANDWIDTH=256000,CODECS=\"mp4a.40.2\"\nhigh/index.m3u8\n")
+	}))
+	defer server.Close()
+
+	masterURL := server.URL + "/master.m3u8"
+	station := NewStation("Test", masterURL, BestQuality)
+
+	startedAt := time.Now()
+	if got := station.StreamURL(); got != masterURL {
+		t.Errorf("StreamURL is %q before the master playlist loads, want %q", got, masterURL)
+	}
+	if got := station.StreamURLs(); len(got) != 1 || got[0] != masterURL {
+		t.Errorf("StreamURLs is %q before the master playlist loads, want just the master", got)
+	}
+	if took := time.Since(startedAt); took > time.Second {
+		t.Errorf("StreamURL waited %v for the master playlist", took)
+	}
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for station.StreamURL() == masterURL {
+		if time.Now().After(deadline) {
+			t.Fatal("master playlist never loaded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	want := []string{server.URL + "/high/index.m3u8", server.URL + "/low/index.m3u8"}
+	got := station.StreamURLs()
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("StreamURLs is %q, want %q", got, want)
+	}
+	if station.StreamURL() != want[0] {
+		t.Errorf("StreamURL is %q, want the best variant %q", station.StreamURL(), want[0])
+	}
+}
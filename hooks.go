@@ -0,0 +1,15 @@
+package main
+
+import "strings"
+
+// stringFlags collects every value of a repeatable flag
+type stringFlags []string
+
+func (f *stringFlags) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
@@ -0,0 +1,242 @@
+// Package hooks tells other programs what's playing by posting JSON to
+// webhooks and running commands whenever something happens.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/utils"
+)
+
+const (
+	// DefaultTimeout limits each webhook request and command
+	DefaultTimeout = 10 * time.Second
+	// DefaultRetries is how many times a failed webhook is tried again
+	DefaultRetries = 2
+
+	// queueSize is how many events can wait for slow hooks before new ones
+	// are dropped
+	queueSize  = 32
+	retryDelay = time.Second
+)
+
+// Event is something hooks are told about
+type Event string
+
+const (
+	// TrackChanged is sent when the current station starts a new track
+	TrackChanged Event = "track_changed"
+	// StationChanged is sent when a different station is tuned in
+	StationChanged Event = "station_changed"
+	// Paused is sent when playback is paused
+	Paused Event = "paused"
+	// Resumed is sent when paused playback continues
+	Resumed Event = "resumed"
+	// Error is sent when track info can't be fetched
+	Error Event = "error"
+)
+
+// Payload is the JSON posted to webhooks and given to commands on stdin
+type Payload struct {
+	Event     Event      `json:"event"`
+	Time      time.Time  `json:"time"`
+	Station   string     `json:"station,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Artist    string     `json:"artist,omitempty"`
+	Album     string     `json:"album,omitempty"`
+	Duration  float64    `json:"duration_seconds,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Hooks runs webhooks and commands for events in the background, one event
+// at a time and in order, so slow hooks never hold up playback
+type Hooks struct {
+	URLs     []string
+	Commands []string
+	Timeout  time.Duration
+	Retries  int
+	// Backoff is how long to wait before the first retry, growing after
+	// each one
+	Backoff time.Duration
+	// ErrorLog logs hooks which fail, if set
+	ErrorLog *log.Logger
+
+	client *http.Client
+	queue  chan Payload
+}
+
+// New creates Hooks posting to urls and running commands, which are run by
+// the shell. Events are handled once Start is called.
+func New(urls, commands []string) *Hooks {
+	return &Hooks{
+		URLs:     urls,
+		Commands: commands,
+		Timeout:  DefaultTimeout,
+		Retries:  DefaultRetries,
+		Backoff:  retryDelay,
+		client:   &http.Client{},
+		queue:    make(chan Payload, queueSize),
+	}
+}
+
+// Start handles events until the program exits
+func (h *Hooks) Start() {
+	go func() {
+		for payload := range h.queue {
+			h.run(payload)
+		}
+	}()
+}
+
+// Fire queues event for the hooks without waiting for them. info and err
+// may be nil. Events are dropped while too many are waiting. A nil Hooks
+// does nothing.
+func (h *Hooks) Fire(event Event, station string, info *models.TrackInfo, err error) {
+	if h == nil {
+		return
+	}
+
+	payload := Payload{Event: event, Time: time.Now(), Station: station}
+	if info != nil {
+		payload.Title = info.Title
+		payload.Artist = info.Artist
+		payload.Album = info.Album
+		payload.Duration = info.Duration
+		if !info.StartedAt.IsZero() {
+			startedAt := info.StartedAt
+			payload.StartedAt = &startedAt
+		}
+	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+
+	select {
+	case h.queue <- payload:
+	default:
+		h.logf("hooks are falling behind, dropped %s event", event)
+	}
+}
+
+func (h *Hooks) run(payload Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		h.logf("failed to marshal hook payload: %v", err)
+		return
+	}
+
+	for _, url := range h.URLs {
+		if err := h.post(url, body); err != nil {
+			h.logf("webhook %s failed: %v", url, err)
+		}
+	}
+	for _, command := range h.Commands {
+		if err := h.exec(command, payload, body); err != nil {
+			h.logf("hook command %q failed: %v", command, err)
+		}
+	}
+}
+
+// post sends body to url, trying again after network errors and responses
+// which may succeed later. Other responses, like 404 Not Found, are given
+// up on at once.
+func (h *Hooks) post(url string, body []byte) error {
+	var err error
+	for attempt := 0; attempt <= h.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(h.Backoff * time.Duration(attempt))
+		}
+
+		err = h.postOnce(url, body)
+		if err == nil {
+			return nil
+		}
+		var statusErr *utils.StatusError
+		if errors.As(err, &statusErr) && !statusErr.Temporary() {
+			return err
+		}
+	}
+	return err
+}
+
+func (h *Hooks) postOnce(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &utils.StatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// exec runs command with the payload's fields in STREAM_PLAYER_ environment
+// variables and its JSON on stdin
+func (h *Hooks) exec(command string, payload Payload, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), environment(payload)...)
+	cmd.Stdin = bytes.NewReader(body)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// environment lists payload's fields as environment variables
+func environment(payload Payload) []string {
+	env := []string{
+		"STREAM_PLAYER_EVENT=" + string(payload.Event),
+		"STREAM_PLAYER_STATION=" + payload.Station,
+		"STREAM_PLAYER_TITLE=" + payload.Title,
+		"STREAM_PLAYER_ARTIST=" + payload.Artist,
+		"STREAM_PLAYER_ALBUM=" + payload.Album,
+		"STREAM_PLAYER_ERROR=" + payload.Error,
+	}
+	if payload.Duration > 0 {
+		env = append(env, "STREAM_PLAYER_DURATION="+strconv.FormatFloat(payload.Duration, 'f', -1, 64))
+	}
+	if payload.StartedAt != nil {
+		env = append(env, "STREAM_PLAYER_STARTED_AT="+payload.StartedAt.Format(time.RFC3339))
+	}
+	return env
+}
+
+func (h *Hooks) logf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	}
+}
@@ -0,0 +1,147 @@
+package hooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// webhook records the payloads posted to it, responding with each status in
+// turn and then 204 No Content
+type webhook struct {
+	mu       sync.Mutex
+	statuses []int
+	payloads []Payload
+	posted   chan struct{}
+}
+
+func newWebhook(statuses ...int) (*webhook, *httptest.Server) {
+	w := &webhook{statuses: statuses, posted: make(chan struct{}, 10)}
+	return w, httptest.NewServer(w)
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer func() { w.posted <- struct{}{} }()
+
+	var payload Payload
+	raw, _ := ioutil.ReadAll(r.Body)
+	if r.Header.Get("Content-Type") != "application/json" || json.Unmarshal(raw, &payload) != nil {
+		rw.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	w.payloads = append(w.payloads, payload)
+
+	status := http.StatusNoContent
+	if len(w.statuses) > 0 {
+		status, w.statuses = w.statuses[0], w.statuses[1:]
+	}
+	rw.WriteHeader(status)
+}
+
+func (w *webhook) posts() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.payloads)
+}
+
+func newTestHooks(url string) *Hooks {
+	h := New([]string{url}, nil)
+	h.Timeout = time.Second
+	h.Backoff = time.Millisecond
+	return h
+}
+
+func TestFirePostsPayload(t *testing.T) {
+	w, server := newWebhook()
+	defer server.Close()
+
+	h := newTestHooks(server.URL)
+	h.Start()
+	startedAt := time.Date(2021, 8, 11, 20, 15, 1, 0, time.UTC)
+	h.Fire(TrackChanged, "WDWNTunes", &models.TrackInfo{
+		Title:     "Main Street Electrical Parade",
+		Artist:    "Disneyland Band",
+		Duration:  184.5,
+		StartedAt: startedAt,
+	}, nil)
+
+	select {
+	case <-w.posted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the webhook wasn't posted to")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.payloads) != 1 {
+		t.Fatalf("posted %d payloads, want 1", len(w.payloads))
+	}
+	got := w.payloads[0]
+	if got.Event != TrackChanged || got.Station != "WDWNTunes" || got.Title != "Main Street Electrical Parade" ||
+		got.Artist != "Disneyland Band" || got.Duration != 184.5 {
+		t.Errorf("posted %+v", got)
+	}
+	if got.StartedAt == nil || !got.StartedAt.Equal(startedAt) {
+		t.Errorf("started at %v, want %v", got.StartedAt, startedAt)
+	}
+}
+
+func TestPostRetries(t *testing.T) {
+	cases := []struct {
+		name     string
+		statuses []int
+		posts    int
+		fails    bool
+	}{
+		{"succeeds", nil, 1, false},
+		{"server errors are retried", []int{http.StatusBadGateway, http.StatusServiceUnavailable}, 3, false},
+		{"too many requests is retried", []int{http.StatusTooManyRequests}, 2, false},
+		{"gives up after the retries", []int{500, 500, 500, 500}, 3, true},
+		{"not found isn't retried", []int{http.StatusNotFound}, 1, true},
+		{"bad request isn't retried", []int{http.StatusBadRequest}, 1, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w, server := newWebhook(c.statuses...)
+			defer server.Close()
+
+			err := newTestHooks(server.URL).post(server.URL, []byte(`{"event":"paused"}`))
+			if (err != nil) != c.fails {
+				t.Errorf("got error %v, want failure %v", err, c.fails)
+			}
+			if posts := w.posts(); posts != c.posts {
+				t.Errorf("posted %d times, want %d", posts, c.posts)
+			}
+		})
+	}
+}
+
+func TestPostRetriesNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	h := newTestHooks(url)
+	attempts := 0
+	h.client.Transport = roundTripper(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	if err := h.post(url, []byte(`{}`)); err == nil {
+		t.Fatal("posting to a closed server succeeded")
+	}
+	if attempts != h.Retries+1 {
+		t.Errorf("tried %d times, want %d", attempts, h.Retries+1)
+	}
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/timeshift"
+	"github.com/codegoalie/stream-player/utils"
+)
+
+// defaultBuffer matches VLC's own default network caching
+const defaultBuffer = time.Second
+
+// latency estimates how far the audio we hear lags behind the metadata
+type latency struct {
+	// buffer is how much audio VLC is asked to cache before playing. It's
+	// an estimate, since VLC doesn't report how much it actually holds.
+	buffer time.Duration
+	// extra covers lag which can't be measured, such as from the CDN
+	extra time.Duration
+	// timeshift is how far playback was paused or rewound, if enabled
+	timeshift *timeshift.Proxy
+}
+
+// delay is how long after a track starts on the server it is heard here
+func (l latency) delay(info *models.TrackInfo) time.Duration {
+	shift := l.timeshift.Behind()
+	return l.buffer + l.extra + shift + time.Duration(info.SyncOffset*float64(time.Second))
+}
+
+// align moves info.StartedAt from the server's clock to when the track is
+// heard locally and returns when info should start being shown. Tracks
+// without a start time are assumed to have started when they were fetched.
+func (l latency) align(fetcher models.InfoFetcher, info *models.TrackInfo, fetchedAt time.Time) time.Time {
+	if info.StartedAt.IsZero() {
+		return fetchedAt.Add(l.delay(info))
+	}
+
+	info.StartedAt = l.heardAt(fetcher, info)
+	return info.StartedAt
+}
+
+// heardAt is when info, which started at info.StartedAt on the server's
+// clock, starts being heard locally
+func (l latency) heardAt(fetcher models.InfoFetcher, info *models.TrackInfo) time.Time {
+	startedAt := info.StartedAt
+	if skew, ok := utils.ClockSkew(fetcher.InfoURL()); ok {
+		startedAt = startedAt.Add(-skew)
+	}
+	return startedAt.Add(l.delay(info))
+}
+
+// playingAt finds the track in recent, most recent first and not yet
+// aligned, which is heard at t. This is further back than the current track
+// when playback is behind live. The track is returned aligned, or nil when
+// none has a start time early enough.
+func (l latency) playingAt(fetcher models.InfoFetcher, recent []*models.TrackInfo, t time.Time) *models.TrackInfo {
+	for _, track := range recent {
+		if track.StartedAt.IsZero() {
+			continue
+		}
+		if heardAt := l.heardAt(fetcher, track); !heardAt.After(t) {
+			aligned := *track
+			aligned.StartedAt = heardAt
+			return &aligned
+		}
+	}
+	return nil
+}
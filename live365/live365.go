@@ -0,0 +1,122 @@
+// Package live365 parses the station info Live365's API sends for the
+// stations it hosts.
+package live365
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// Response is the station info from api.live365.com/station/{id}
+type Response struct {
+	CurrentTrack Track   `json:"current-track"`
+	LastPlayed   []Track `json:"last-played"`
+}
+
+// Track is a track a station is playing or has played
+type Track struct {
+	Title     string  `json:"title"`
+	Artist    string  `json:"artist"`
+	Duration  float64 `json:"duration"`
+	StartedAt Time    `json:"start"`
+	EndedAt   Time    `json:"end"`
+	Art       string  `json:"art"`
+
+	SyncOffset Seconds `json:"sync_offset"`
+}
+
+// Parse unmarshals a station info payload
+func Parse(raw []byte) (*Response, error) {
+	resp := &Response{}
+	err := json.Unmarshal(raw, resp)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal Live365 station info: %w", err)
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// ParseTrackInfo parses a station info payload into its current track
+func ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
+	resp, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.CurrentTrack.TrackInfo(), nil
+}
+
+// ParseHistory parses a station info payload into its current track
+// followed by the tracks played before it
+func ParseHistory(raw []byte) ([]*models.TrackInfo, error) {
+	resp, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []*models.TrackInfo{resp.CurrentTrack.TrackInfo()}
+	for _, track := range resp.LastPlayed {
+		history = append(history, track.TrackInfo())
+	}
+
+	return history, nil
+}
+
+// TrackInfo converts t into a TrackInfo
+func (t Track) TrackInfo() *models.TrackInfo {
+	return &models.TrackInfo{
+		Title:      t.Title,
+		Artist:     t.Artist,
+		Duration:   t.Duration,
+		StartedAt:  time.Time(t.StartedAt),
+		ArtURL:     t.Art,
+		SyncOffset: float64(t.SyncOffset),
+	}
+}
+
+// Time is a time Live365 sends like 2021-08-11 20:15:01.482000+00:00
+type Time time.Time
+
+func (t *Time) UnmarshalJSON(b []byte) error {
+	conv := strings.Trim(string(b), `"`)
+	if conv == "" || conv == "null" {
+		*t = Time{}
+		return nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, strings.Replace(conv, " ", "T", 1))
+	if err != nil {
+		err = fmt.Errorf("failed to parse Live365 time: %w", err)
+		return err
+	}
+
+	*t = Time(parsed)
+	return nil
+}
+
+// Seconds is a number of seconds which Live365 sends either as a number or
+// a string
+type Seconds float64
+
+func (s *Seconds) UnmarshalJSON(b []byte) error {
+	conv := strings.Trim(string(b), `"`)
+	if conv == "" || conv == "null" {
+		*s = 0
+		return nil
+	}
+
+	parsed, err := strconv.ParseFloat(conv, 64)
+	if err != nil {
+		err = fmt.Errorf("failed to parse Live365 seconds: %w", err)
+		return err
+	}
+
+	*s = Seconds(parsed)
+	return nil
+}
@@ -0,0 +1,60 @@
+package live365
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	raw := []byte(`{
+		"current-track": {
+			"title": "Main Street Electrical Parade",
+			"artist": "Disneyland Band",
+			"duration": 180.5,
+			"start": "2021-08-11 20:15:01.482000+00:00",
+			"end": null,
+			"art": "https://example.com/art.jpg",
+			"sync_offset": "4.5"
+		},
+		"last-played": [
+			{"title": "Station ID", "duration": 8.75, "start": "", "sync_offset": null}
+		]
+	}`)
+
+	history, err := ParseHistory(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("got %d tracks, want 2", len(history))
+	}
+
+	current := history[0]
+	wantStart := time.Date(2021, 8, 11, 20, 15, 1, 482000000, time.UTC)
+	if !current.StartedAt.Equal(wantStart) {
+		t.Errorf("started at %v, want %v", current.StartedAt, wantStart)
+	}
+	if current.SyncOffset != 4.5 {
+		t.Errorf("sync offset %v, want 4.5", current.SyncOffset)
+	}
+	if current.ArtURL != "https://example.com/art.jpg" || current.Duration != 180.5 {
+		t.Errorf("got %+v", current)
+	}
+
+	previous := history[1]
+	if !previous.StartedAt.IsZero() || previous.SyncOffset != 0 {
+		t.Errorf("empty fields should be zero, got %+v", previous)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	for _, raw := range []string{
+		`{"current-track": {"start": "yesterday"}}`,
+		`{"current-track": {"sync_offset": "soon"}}`,
+		`[`,
+	} {
+		if _, err := ParseTrackInfo([]byte(raw)); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	vlc "github.com/adrg/libvlc-go/v3"
+	"github.com/codegoalie/stream-player/metrics"
+)
+
+var (
+	metadataFetches = metrics.Default.NewCounter(
+		"stream_player_metadata_fetches_total",
+		"Track info fetches by station, host and result (success or failure).",
+		"station", "host", "result",
+	)
+	streamReconnects = metrics.Default.NewCounter(
+		"stream_player_stream_reconnects_total",
+		"Times the current station's stream was opened again after failing or stalling.",
+		"station",
+	)
+	streamSeeks = metrics.Default.NewCounter(
+		"stream_player_seeks_total",
+		"Times the current station was resumed, rewound or brought back to live.",
+		"station",
+	)
+	currentStation = metrics.Default.NewGauge(
+		"stream_player_current_station",
+		"Set to 1 for the station being played.",
+		"station",
+	)
+	vlcStats = metrics.Default.NewGauge(
+		"stream_player_vlc_stat",
+		"Playback statistics reported by VLC for the current stream, by statistic.",
+		"stat",
+	)
+	requestDuration = metrics.Default.NewHistogram(
+		"stream_player_http_request_duration_seconds",
+		"Time taken by HTTP GET requests for track info, by host.",
+		metrics.DefBuckets,
+		"host",
+	)
+)
+
+// lastTrackChange is when the current station last started a new track
+var lastTrackChange struct {
+	sync.Mutex
+	at time.Time
+}
+
+func init() {
+	metrics.Default.NewGaugeFunc(
+		"stream_player_seconds_since_track_change",
+		"Seconds since the current station last started a new track.",
+		func() float64 {
+			lastTrackChange.Lock()
+			defer lastTrackChange.Unlock()
+			if lastTrackChange.at.IsZero() {
+				return 0
+			}
+			return time.Since(lastTrackChange.at).Seconds()
+		},
+	)
+}
+
+// recordFetch counts a track info fetch from station's infoURL
+func recordFetch(station, infoURL string, err error) {
+	host := ""
+	if u, parseErr := url.Parse(infoURL); parseErr == nil {
+		host = u.Host
+	}
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	metadataFetches.Inc(station, host, result)
+}
+
+// recordRequest times a request for track info, as utils.Client's Observe
+func recordRequest(host string, d time.Duration) {
+	requestDuration.Observe(d.Seconds(), host)
+}
+
+// recordStation marks station as the one being played
+func recordStation(station string) {
+	currentStation.Reset()
+	currentStation.Set(1, station)
+}
+
+func recordTrackChange() {
+	lastTrackChange.Lock()
+	defer lastTrackChange.Unlock()
+	lastTrackChange.at = time.Now()
+}
+
+// recordVLCStats records the statistics VLC keeps for media
+func recordVLCStats(media *vlc.Media) {
+	stats, err := media.Stats()
+	if err != nil {
+		return
+	}
+
+	vlcStats.Set(float64(stats.ReadBytes), "read_bytes")
+	vlcStats.Set(stats.InputBitRate, "input_bitrate")
+	vlcStats.Set(float64(stats.DemuxReadBytes), "demux_read_bytes")
+	vlcStats.Set(stats.DemuxBitRate, "demux_bitrate")
+	vlcStats.Set(float64(stats.DemuxCorrupted), "demux_corrupted")
+	vlcStats.Set(float64(stats.DemuxDiscontinuity), "demux_discontinuity")
+	vlcStats.Set(float64(stats.DecodedAudio), "decoded_audio")
+	vlcStats.Set(float64(stats.PlayedAudioBuffers), "played_audio_buffers")
+	vlcStats.Set(float64(stats.LostAudioBuffers), "lost_audio_buffers")
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	fetches := r.NewCounter("fetches_total", "Fetches by station.\nA second line with a \\.", "station")
+	station := r.NewGauge("current_station", "The station playing.", "station")
+	r.NewGaugeFunc("uptime_seconds", "Seconds running.", func() float64 { return 1.5 })
+	requests := r.NewHistogram("request_seconds", "Request times.", []float64{.1, 1}, "host")
+
+	fetches.Inc(`Sorcer "Main" \ Stream`)
+	fetches.Add(2, "line\nbreak")
+	fetches.Add(-1, "line\nbreak")
+	station.Set(1, "Resort TV")
+	station.Reset()
+	station.Set(1, "Background")
+	requests.Observe(.05, "example.com")
+	requests.Observe(.5, "example.com")
+	requests.Observe(3, "example.com")
+
+	var out bytes.Buffer
+	if err := r.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP fetches_total Fetches by station.\nA second line with a \\.
+# TYPE fetches_total counter
+fetches_total{station="Sorcer \"Main\" \\ Stream"} 1
+fetches_total{station="line\nbreak"} 2
+# HELP current_station The station playing.
+# TYPE current_station gauge
+current_station{station="Background"} 1
+# HELP uptime_seconds Seconds running.
+# TYPE uptime_seconds gauge
+uptime_seconds 1.5
+# HELP request_seconds Request times.
+# TYPE request_seconds histogram
+request_seconds_bucket{host="example.com",le="0.1"} 1
+request_seconds_bucket{host="example.com",le="1"} 2
+request_seconds_bucket{host="example.com",le="+Inf"} 3
+request_seconds_sum{host="example.com"} 3.55
+request_seconds_count{host="example.com"} 3
+`
+	if got := out.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	NewRegistry().NewCounter("fetches_total", "Fetches.", "station").Inc()
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// mirrorRetryDelay is how long to wait before going around a station's
+// mirrors again once every one has failed
+const mirrorRetryDelay = 5 * time.Second
+
+// streamReport is the player telling the main loop how the stream it was
+// given is doing
+type streamReport struct {
+	url string
+	// err is why the stream stopped playing, or nil once audio arrives
+	err error
+}
+
+// mirrors picks which of the current station's stream URLs to play, moving
+// on to the next when one stops working, and remembers each station's
+// last working URL between runs
+type mirrors struct {
+	path string
+
+	mu      sync.Mutex
+	working map[string]string
+	station string
+	urls    []string
+	index   int
+	// failures counts the URLs which have failed since one last worked
+	failures int
+}
+
+// newMirrors loads the URLs which last worked from path. A missing or
+// unreadable file starts with each station's first URL.
+func newMirrors(path string) *mirrors {
+	m := &mirrors{path: path, working: map[string]string{}}
+	if raw, err := ioutil.ReadFile(path); err == nil {
+		_ = json.Unmarshal(raw, &m.working)
+	}
+	return m
+}
+
+// defaultMirrorsPath is where working mirrors are kept between runs
+func defaultMirrorsPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "stream-player", "mirrors.json")
+}
+
+// streamURLs lists every URL source can be streamed from, in order of
+// preference
+func streamURLs(source models.MediaSource) []string {
+	if provider, ok := source.(models.MirrorProvider); ok {
+		if urls := provider.StreamURLs(); len(urls) > 0 {
+			return urls
+		}
+	}
+	return []string{source.StreamURL()}
+}
+
+// start switches to source, returning the URL to play: the one which last
+// worked if the station still lists it, or else its first
+func (m *mirrors) start(source models.MediaSource) string {
+	urls := streamURLs(source)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.station = source.Name()
+	m.urls = urls
+	m.index = 0
+	m.failures = 0
+	for i, u := range urls {
+		if u == m.working[m.station] {
+			m.index = i
+		}
+	}
+	return m.urls[m.index]
+}
+
+// failed moves on from the URL being played, returning the next one to play
+// and how long to wait before playing it. There's only a wait once every
+// URL has failed in a row.
+func (m *mirrors) failed() (string, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures++
+	m.index = (m.index + 1) % len(m.urls)
+
+	var wait time.Duration
+	if m.failures >= len(m.urls) {
+		m.failures = 0
+		wait = mirrorRetryDelay
+	}
+	return m.urls[m.index], wait
+}
+
+// reported handles a streamReport about the stream playing from current,
+// returning the URL to play next, or "" to keep playing, and how long to
+// wait before playing it. Reports about streams since switched away from
+// are ignored.
+func (m *mirrors) reported(report streamReport, current string) (string, time.Duration) {
+	if report.url != current {
+		return "", 0
+	}
+	if report.err == nil {
+		m.worked()
+		return "", 0
+	}
+	return m.failed()
+}
+
+// worked remembers the URL being played as the station's working one
+func (m *mirrors) worked() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures = 0
+	active := m.urls[m.index]
+	if m.working[m.station] == active {
+		return
+	}
+	m.working[m.station] = active
+	m.save()
+}
+
+// save writes the working URLs to m.path. m.mu must be held.
+func (m *mirrors) save() {
+	if m.path == "" {
+		return
+	}
+
+	raw, err := json.Marshal(m.working)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return
+	}
+	_ = ioutil.WriteFile(m.path, raw, 0644)
+}
+
+// status describes the mirror being played for stations with more than
+// one, or is empty
+func (m *mirrors) status() string {
+	if m == nil {
+		return ""
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.urls) < 2 {
+		return ""
+	}
+	host := m.urls[m.index]
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return fmt.Sprintf("Mirror %d of %d (%s)", m.index+1, len(m.urls), host)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+const (
+	primaryURL   = "https://primary.example.com/stream"
+	secondaryURL = "https://secondary.example.com/stream"
+	tertiaryURL  = "https://tertiary.example.com/stream"
+)
+
+var errStalled = errors.New("stream stalled")
+
+type mirroredStation struct {
+	urls []string
+}
+
+func (s mirroredStation) Name() string      { return "Mirrored" }
+func (s mirroredStation) StreamURL() string { return s.urls[0] }
+func (s mirroredStation) InfoURL() string   { return "https://example.com/info" }
+func (s mirroredStation) ParseTrackInfo([]byte) (*models.TrackInfo, error) {
+	return &models.TrackInfo{}, nil
+}
+func (s mirroredStation) StreamURLs() []string { return s.urls }
+
+var threeMirrors = mirroredStation{urls: []string{primaryURL, secondaryURL, tertiaryURL}}
+
+// play feeds reports to m the way the main loop does, returning the URL
+// being played afterwards and every wait before playing one
+func play(m *mirrors, current string, reports ...streamReport) (string, []time.Duration) {
+	waits := []time.Duration{}
+	for _, report := range reports {
+		if report.url == "" {
+			report.url = current
+		}
+		next, wait := m.reported(report, current)
+		if next == "" {
+			continue
+		}
+		if wait > 0 {
+			waits = append(waits, wait)
+		}
+		current = next
+	}
+	return current, waits
+}
+
+func TestMirrorsFailOver(t *testing.T) {
+	m := newMirrors("")
+	current := m.start(threeMirrors)
+	if current != primaryURL {
+		t.Fatalf("started on %s, want the primary", current)
+	}
+
+	current, waits := play(m, current, streamReport{err: errStalled})
+	if current != secondaryURL || len(waits) != 0 {
+		t.Errorf("failed over to %s after %v, want %s at once", current, waits, secondaryURL)
+	}
+	if status := m.status(); status != "Mirror 2 of 3 (secondary.example.com)" {
+		t.Errorf("status is %q", status)
+	}
+
+	// a late report from the primary doesn't move on from the secondary
+	current, _ = play(m, current, streamReport{url: primaryURL, err: errStalled})
+	if current != secondaryURL {
+		t.Errorf("switched to %s over a stream no longer played", current)
+	}
+}
+
+func TestMirrorsComeBackToPrimary(t *testing.T) {
+	m := newMirrors("")
+	current := m.start(threeMirrors)
+
+	// the secondary plays for a while, then the tertiary is down too
+	current, waits := play(m, current,
+		streamReport{err: errStalled},
+		streamReport{},
+		streamReport{err: errStalled},
+		streamReport{err: errStalled},
+	)
+	if current != primaryURL || len(waits) != 0 {
+		t.Errorf("came around to %s after %v, want the primary at once", current, waits)
+	}
+
+	// the primary works again, so the station starts there next time
+	play(m, current, streamReport{})
+	if current := m.start(threeMirrors); current != primaryURL {
+		t.Errorf("restarted on %s, want the primary", current)
+	}
+}
+
+func TestMirrorsGiveUpOnEveryMirror(t *testing.T) {
+	m := newMirrors("")
+	current := m.start(threeMirrors)
+
+	current, waits := play(m, current,
+		streamReport{err: errStalled},
+		streamReport{err: errStalled},
+		streamReport{err: errStalled},
+	)
+	if current != primaryURL {
+		t.Errorf("retrying %s, want the primary", current)
+	}
+	if len(waits) != 1 || waits[0] != mirrorRetryDelay {
+		t.Errorf("waited %v once every mirror failed, want [%v]", waits, mirrorRetryDelay)
+	}
+
+	// each time around waits again
+	_, waits = play(m, current,
+		streamReport{err: errStalled},
+		streamReport{err: errStalled},
+		streamReport{err: errStalled},
+	)
+	if len(waits) != 1 {
+		t.Errorf("waited %v the second time around, want once", waits)
+	}
+}
+
+func TestMirrorsRememberWorkingURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirrors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stream-player", "mirrors.json")
+
+	m := newMirrors(path)
+	play(m, m.start(threeMirrors), streamReport{err: errStalled}, streamReport{})
+
+	// the next run starts on the mirror which last worked
+	if current := newMirrors(path).start(threeMirrors); current != secondaryURL {
+		t.Errorf("next run started on %s, want %s", current, secondaryURL)
+	}
+	// unless the station stopped listing it
+	moved := mirroredStation{urls: []string{primaryURL, tertiaryURL}}
+	if current := newMirrors(path).start(moved); current != primaryURL {
+		t.Errorf("started on %s, want the primary", current)
+	}
+}
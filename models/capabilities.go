@@ -0,0 +1,40 @@
+package models
+
+import "context"
+
+// The interfaces below are optional capabilities of a MediaSource. Callers
+// discover them with a type assertion and fall back to the plain MediaSource
+// behavior when a station doesn't implement them.
+
+// HistoryProvider is a MediaSource whose info payload also lists the tracks
+// played before the current one
+type HistoryProvider interface {
+	// ParseHistory parses the payload from InfoURL into tracks, most recent
+	// first, including the current track
+	ParseHistory([]byte) ([]*TrackInfo, error)
+}
+
+// MirrorProvider is a MediaSource which can be streamed from more than one
+// URL
+type MirrorProvider interface {
+	// StreamURLs lists every URL to stream audio in order of preference,
+	// starting with StreamURL
+	StreamURLs() []string
+}
+
+// ContextFetcher is a MediaSource which fetches its own TrackInfo rather
+// than through an HTTP GET of InfoURL, such as an HLS station reading its
+// playlist's tags
+type ContextFetcher interface {
+	FetchTrackInfo(ctx context.Context) (*TrackInfo, error)
+}
+
+// Describer is a MediaSource which can tell listeners more about itself
+type Describer interface {
+	// Description tells listeners what the stream plays
+	Description() string
+	// Homepage is the stream's website
+	Homepage() string
+	// Genre is the stream's style of music
+	Genre() string
+}
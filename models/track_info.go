@@ -8,6 +8,20 @@ type TrackInfo struct {
 	Artist    string
 	Duration  float64
 	StartedAt time.Time
+
+	// DurationEstimated is true when Duration was learned from earlier plays
+	// rather than reported by the provider
+	DurationEstimated bool
+	// MediaType is the provider's content code, e.g. MUS or COM for SAM
+	// stations, and empty when the provider doesn't report one
+	MediaType string
+	// SyncOffset is how many seconds the provider says its audio lags behind
+	// StartedAt
+	SyncOffset float64
+	// ArtURL is the address of the track's artwork if the provider has one
+	ArtURL string
+	// ArtPath is the locally cached copy of ArtURL once downloaded
+	ArtPath string
 }
 
 type InfoFetcher interface {
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/mqtt"
+)
+
+const (
+	mqttRetryDelay      = 5 * time.Second
+	mqttPublishInterval = 10 * time.Second
+)
+
+// mqttState is what's playing, published retained to the state topic
+type mqttState struct {
+	Station  string  `json:"station"`
+	Title    string  `json:"title"`
+	Artist   string  `json:"artist"`
+	Album    string  `json:"album"`
+	Position float64 `json:"position_seconds"`
+	Duration float64 `json:"duration_seconds"`
+	Volume   int     `json:"volume"`
+	Paused   bool    `json:"paused"`
+
+	startedAt time.Time
+}
+
+// mqttBridge publishes what's playing to an MQTT broker and runs commands
+// sent to its command topic, reconnecting whenever the broker goes away
+type mqttBridge struct {
+	broker string
+	// topic prefixes the state, availability and command topics
+	topic string
+	// discovery is Home Assistant's discovery prefix or empty to not
+	// announce the player
+	discovery string
+	commands  commands
+
+	mu      sync.Mutex
+	state   mqttState
+	changed chan struct{}
+}
+
+func newMQTTBridge(broker, topic, discovery string, cmds commands) *mqttBridge {
+	return &mqttBridge{
+		broker:    broker,
+		topic:     strings.TrimSuffix(topic, "/"),
+		discovery: strings.TrimSuffix(discovery, "/"),
+		commands:  cmds,
+		state:     mqttState{Volume: fullVolume},
+		changed:   make(chan struct{}, 1),
+	}
+}
+
+// update changes the state with change and publishes it soon after without
+// waiting. A nil bridge does nothing.
+func (b *mqttBridge) update(change func(*mqttState)) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	change(&b.state)
+	b.mu.Unlock()
+
+	select {
+	case b.changed <- struct{}{}:
+	default:
+	}
+}
+
+// track sets the state's track from info
+func (s *mqttState) track(info *models.TrackInfo) {
+	s.Title = info.Title
+	s.Artist = info.Artist
+	s.Album = info.Album
+	s.Duration = info.Duration
+	s.startedAt = info.StartedAt
+}
+
+// run connects to the broker and keeps publishing until the program exits
+func (b *mqttBridge) run() {
+	for {
+		err := b.session()
+		fmt.Fprintf(b.commands.out, "MQTT: %s, reconnecting\n", err)
+		time.Sleep(mqttRetryDelay)
+	}
+}
+
+// session publishes state over a single connection until it's lost
+func (b *mqttBridge) session() error {
+	hostname, _ := os.Hostname()
+	// Home Assistant only allows letters, digits, _ and - in IDs
+	clientID := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, "stream-player-"+hostname)
+	availability := b.topic + "/availability"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	client, err := mqtt.Connect(ctx, b.broker, mqtt.Options{
+		ClientID: clientID,
+		Will:     &mqtt.Message{Topic: availability, Payload: []byte("offline"), Retained: true},
+	})
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if b.discovery != "" {
+		for _, msg := range b.discoveryMessages(clientID) {
+			if err := client.Publish(msg); err != nil {
+				return err
+			}
+		}
+	}
+	if err := client.Publish(mqtt.Message{Topic: availability, Payload: []byte("online"), Retained: true}); err != nil {
+		return err
+	}
+
+	// commands wait on the main loop, so they're run one at a time in the
+	// order they arrived, away from reading and publishing
+	commands := make(chan string, 10)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case command := <-commands:
+				b.commands.run(command)
+			case <-done:
+				return
+			}
+		}
+	}()
+	err = client.Subscribe(b.topic+"/command", func(msg mqtt.Message) {
+		select {
+		case commands <- string(msg.Payload):
+		default:
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(mqttPublishInterval)
+	defer ticker.Stop()
+	for {
+		if err := client.Publish(b.stateMessage()); err != nil {
+			return err
+		}
+
+		select {
+		case <-b.changed:
+		case <-ticker.C:
+		case <-client.Done():
+			return client.Err()
+		}
+	}
+}
+
+func (b *mqttBridge) stateMessage() mqtt.Message {
+	b.mu.Lock()
+	state := b.state
+	b.mu.Unlock()
+
+	if !state.startedAt.IsZero() {
+		state.Position = time.Since(state.startedAt).Truncate(time.Second).Seconds()
+		if state.Duration > 0 && state.Position > state.Duration {
+			state.Position = state.Duration
+		}
+	}
+
+	payload, _ := json.Marshal(state)
+	return mqtt.Message{Topic: b.topic + "/state", Payload: payload, Retained: true}
+}
+
+// discoveryMessages announce the player to Home Assistant as a device with
+// sensors for the station and track, a number for the volume and buttons
+// for the commands. Home Assistant's MQTT integration has no media player,
+// so the player is made of these instead.
+func (b *mqttBridge) discoveryMessages(clientID string) []mqtt.Message {
+	device := map[string]interface{}{
+		"identifiers": []string{clientID},
+		"name":        "Stream Player",
+	}
+	entity := func(component, object, name string, config map[string]interface{}) mqtt.Message {
+		config["name"] = name
+		config["unique_id"] = clientID + "_" + object
+		config["availability_topic"] = b.topic + "/availability"
+		config["device"] = device
+
+		payload, _ := json.Marshal(config)
+		return mqtt.Message{
+			Topic:    b.discovery + "/" + component + "/" + clientID + "/" + object + "/config",
+			Payload:  payload,
+			Retained: true,
+		}
+	}
+	state := b.topic + "/state"
+	command := b.topic + "/command"
+	button := func(object, name, payload string) mqtt.Message {
+		return entity("button", object, name, map[string]interface{}{
+			"command_topic": command,
+			"payload_press": payload,
+		})
+	}
+
+	return []mqtt.Message{
+		entity("sensor", "station", "Station", map[string]interface{}{
+			"state_topic":    state,
+			"value_template": "{{ value_json.station }}",
+		}),
+		entity("sensor", "track", "Track", map[string]interface{}{
+			"state_topic":           state,
+			"value_template":        "{{ value_json.title }}{% if value_json.artist %} - {{ value_json.artist }}{% endif %}",
+			"json_attributes_topic": state,
+		}),
+		entity("binary_sensor", "paused", "Paused", map[string]interface{}{
+			"state_topic":    state,
+			"value_template": "{{ 'ON' if value_json.paused else 'OFF' }}",
+		}),
+		entity("number", "volume", "Volume", map[string]interface{}{
+			"state_topic":         state,
+			"value_template":      "{{ value_json.volume }}",
+			"command_topic":       command,
+			"command_template":    "volume {{ value }}",
+			"min":                 0,
+			"max":                 maxVolume,
+			"step":                1,
+			"unit_of_measurement": "%",
+		}),
+		button("next", "Next station", "next"),
+		button("previous", "Previous station", "previous"),
+		button("pause", "Pause or resume", "pause"),
+		button("live", "Go live", "live"),
+	}
+}
@@ -0,0 +1,238 @@
+package mqtt
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Broker is a minimal in-process broker for trying clients out without a
+// real one. It relays QoS 0 messages, keeps retained messages and publishes
+// wills, but has no authentication or persistence.
+type Broker struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	sessions map[*session]struct{}
+	retained map[string]Message
+}
+
+type session struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	filters []string
+	will    *Message
+}
+
+// NewBroker starts a broker listening on addr, e.g. "127.0.0.1:0"
+func NewBroker(addr string) (*Broker, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		err = fmt.Errorf("failed to listen for mqtt: %w", err)
+		return nil, err
+	}
+
+	b := &Broker{
+		listener: listener,
+		sessions: map[*session]struct{}{},
+		retained: map[string]Message{},
+	}
+	go b.accept()
+	return b, nil
+}
+
+// URL is the broker's address for Connect
+func (b *Broker) URL() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// Retained is the message retained on topic, if any
+func (b *Broker) Retained(topic string) (Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	msg, ok := b.retained[topic]
+	return msg, ok
+}
+
+// Publish sends msg to subscribed clients as if a client published it
+func (b *Broker) Publish(msg Message) {
+	b.mu.Lock()
+	if msg.Retained {
+		if len(msg.Payload) == 0 {
+			delete(b.retained, msg.Topic)
+		} else {
+			b.retained[msg.Topic] = msg
+		}
+	}
+	var targets []*session
+	for s := range b.sessions {
+		if s.subscribed(msg.Topic) {
+			targets = append(targets, s)
+		}
+	}
+	b.mu.Unlock()
+
+	// subscribers hear live messages without the retained flag
+	live := msg
+	live.Retained = false
+	for _, s := range targets {
+		s.write(live.publish())
+	}
+}
+
+// Close stops the broker and disconnects every client
+func (b *Broker) Close() error {
+	err := b.listener.Close()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.sessions {
+		s.conn.Close()
+	}
+	return err
+}
+
+func (b *Broker) accept() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.serve(conn)
+	}
+}
+
+func (b *Broker) serve(conn net.Conn) {
+	s := &session{conn: conn}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	p, err := readPacket(reader)
+	if err != nil || p.kind != connectPacket {
+		return
+	}
+	s.will = parseWill(p.body)
+	s.write(packet{kind: connackPacket, body: []byte{0, 0}})
+
+	b.mu.Lock()
+	b.sessions[s] = struct{}{}
+	b.mu.Unlock()
+
+	clean := false
+	defer func() {
+		b.mu.Lock()
+		delete(b.sessions, s)
+		b.mu.Unlock()
+		if !clean && s.will != nil {
+			b.Publish(*s.will)
+		}
+	}()
+
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch p.kind {
+		case publishPacket:
+			msg, _, err := parsePublish(p)
+			if err != nil {
+				return
+			}
+			b.Publish(msg)
+		case subscribePacket:
+			if err := b.subscribe(s, p.body); err != nil {
+				return
+			}
+		case pingreqPacket:
+			s.write(packet{kind: pingrespPacket})
+		case disconnectPacket:
+			clean = true
+			return
+		}
+	}
+}
+
+// subscribe adds the filters in a SUBSCRIBE packet's body to s, granting
+// QoS 0, and sends s the messages retained on them
+func (b *Broker) subscribe(s *session, body []byte) error {
+	if len(body) < 2 {
+		return fmt.Errorf("truncated subscribe")
+	}
+	ack := append([]byte(nil), body[:2]...)
+	rest := body[2:]
+
+	var filters []string
+	for len(rest) > 0 {
+		filter, after, err := readString(rest)
+		if err != nil || len(after) < 1 {
+			return fmt.Errorf("truncated subscribe")
+		}
+		filters = append(filters, filter)
+		ack = append(ack, 0)
+		rest = after[1:]
+	}
+
+	b.mu.Lock()
+	s.filters = append(s.filters, filters...)
+	var retained []Message
+	for topic, msg := range b.retained {
+		for _, filter := range filters {
+			if Match(filter, topic) {
+				retained = append(retained, msg)
+				break
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	s.write(packet{kind: subackPacket, body: ack})
+	for _, msg := range retained {
+		s.write(msg.publish())
+	}
+	return nil
+}
+
+// parseWill reads the will from a CONNECT packet's body, if it has one
+func parseWill(body []byte) *Message {
+	_, rest, err := readString(body)
+	if err != nil || len(rest) < 4 {
+		return nil
+	}
+	flags := rest[1]
+	rest = rest[4:]
+	if flags&0x04 == 0 {
+		return nil
+	}
+
+	_, rest, err = readString(rest)
+	if err != nil {
+		return nil
+	}
+	topic, rest, err := readString(rest)
+	if err != nil {
+		return nil
+	}
+	payload, _, err := readString(rest)
+	if err != nil {
+		return nil
+	}
+	return &Message{Topic: topic, Payload: []byte(payload), Retained: flags&0x20 != 0}
+}
+
+func (s *session) subscribed(topic string) bool {
+	for _, filter := range s.filters {
+		if Match(filter, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *session) write(p packet) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.Write(p.encode())
+}
@@ -0,0 +1,226 @@
+package mqtt
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+const timeout = 2 * time.Second
+
+func startBroker(t *testing.T) *Broker {
+	t.Helper()
+	broker, err := NewBroker("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return broker
+}
+
+func dial(t *testing.T, broker *Broker, opts Options) *Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client, err := Connect(ctx, broker.URL(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// subscribe subscribes client to filter, sending what it receives to the
+// returned channel, and waits for the broker to add the subscription
+func subscribe(t *testing.T, broker *Broker, client *Client, filter string) <-chan Message {
+	t.Helper()
+	received := make(chan Message, 10)
+	err := client.Subscribe(filter, func(msg Message) {
+		received <- msg
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for !broker.hasFilter(filter) {
+		if time.Now().After(deadline) {
+			t.Fatalf("broker never subscribed to %q", filter)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return received
+}
+
+func (b *Broker) hasFilter(filter string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.sessions {
+		for _, f := range s.filters {
+			if f == filter {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func receive(t *testing.T, messages <-chan Message) Message {
+	t.Helper()
+	select {
+	case msg := <-messages:
+		return msg
+	case <-time.After(timeout):
+		t.Fatal("no message arrived")
+		return Message{}
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	broker := startBroker(t)
+	defer broker.Close()
+	subscriber := dial(t, broker, Options{ClientID: "subscriber"})
+	defer subscriber.Close()
+	publisher := dial(t, broker, Options{ClientID: "publisher"})
+	defer publisher.Close()
+	messages := subscribe(t, broker, subscriber, "player/+/state")
+
+	if err := publisher.Publish(Message{Topic: "player/kitchen/volume", Payload: []byte("80")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Publish(Message{Topic: "player/kitchen/state", Payload: []byte("playing")}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := receive(t, messages)
+	if msg.Topic != "player/kitchen/state" || string(msg.Payload) != "playing" || msg.Retained {
+		t.Errorf("received %+v, want the live state message", msg)
+	}
+	select {
+	case msg := <-messages:
+		t.Errorf("received %+v which doesn't match the filter", msg)
+	default:
+	}
+}
+
+func TestRetained(t *testing.T) {
+	broker := startBroker(t)
+	defer broker.Close()
+	publisher := dial(t, broker, Options{ClientID: "publisher"})
+	defer publisher.Close()
+
+	retained := Message{Topic: "player/state", Payload: []byte(`{"paused":false}`), Retained: true}
+	if err := publisher.Publish(retained); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, ok := broker.Retained("player/state"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("broker didn't retain the message")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// subscribing later still hears the retained message, flagged as such
+	subscriber := dial(t, broker, Options{ClientID: "subscriber"})
+	defer subscriber.Close()
+	msg := receive(t, subscribe(t, broker, subscriber, "player/#"))
+	if msg.Topic != retained.Topic || string(msg.Payload) != string(retained.Payload) || !msg.Retained {
+		t.Errorf("received %+v, want %+v", msg, retained)
+	}
+
+	// an empty retained message clears it
+	broker.Publish(Message{Topic: "player/state", Retained: true})
+	if msg, ok := broker.Retained("player/state"); ok {
+		t.Errorf("still retained %+v after clearing", msg)
+	}
+}
+
+func TestWillOnLostConnection(t *testing.T) {
+	broker := startBroker(t)
+	defer broker.Close()
+	observer := dial(t, broker, Options{ClientID: "observer"})
+	defer observer.Close()
+	messages := subscribe(t, broker, observer, "player/availability")
+
+	will := &Message{Topic: "player/availability", Payload: []byte("offline"), Retained: true}
+	player := dial(t, broker, Options{ClientID: "player", Will: will})
+	defer player.Close()
+	// drop the connection without saying goodbye
+	player.conn.Close()
+
+	msg := receive(t, messages)
+	if msg.Topic != will.Topic || string(msg.Payload) != "offline" {
+		t.Errorf("received %+v, want the will", msg)
+	}
+	if msg, ok := broker.Retained(will.Topic); !ok || string(msg.Payload) != "offline" {
+		t.Errorf("retained %+v, want the will", msg)
+	}
+	select {
+	case <-player.Done():
+	case <-time.After(timeout):
+		t.Error("client didn't notice its connection was lost")
+	}
+}
+
+func TestNoWillOnClose(t *testing.T) {
+	broker := startBroker(t)
+	defer broker.Close()
+	observer := dial(t, broker, Options{ClientID: "observer"})
+	defer observer.Close()
+	messages := subscribe(t, broker, observer, "player/#")
+
+	will := &Message{Topic: "player/availability", Payload: []byte("offline")}
+	player := dial(t, broker, Options{ClientID: "player", Will: will})
+	defer player.Close()
+	if err := player.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := player.Publish(Message{Topic: "player/state"}); err != ErrClosed {
+		t.Errorf("publishing after closing returned %v, want %v", err, ErrClosed)
+	}
+
+	// the observer is the only session left once the broker has handled
+	// the disconnect
+	deadline := time.Now().Add(timeout)
+	for broker.sessionCount() > 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("broker never dropped the closed session")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case msg := <-messages:
+		t.Errorf("received %+v after a clean close", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (b *Broker) sessionCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.sessions)
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"player/state", "player/state", true},
+		{"player/state", "player/command", false},
+		{"player/+", "player/state", true},
+		{"player/+", "player/state/extra", false},
+		{"+/state", "player/state", true},
+		{"player/#", "player/state/extra", true},
+		{"player/#", "player", true},
+		{"#", "player/state", true},
+		{"player", "player/state", false},
+	}
+	for _, test := range tests {
+		if got := Match(test.filter, test.topic); got != test.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", test.filter, test.topic, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// packet types, from the fixed header's upper four bits
+const (
+	connectPacket    = 1
+	connackPacket    = 2
+	publishPacket    = 3
+	pubackPacket     = 4
+	subscribePacket  = 8
+	subackPacket     = 9
+	pingreqPacket    = 12
+	pingrespPacket   = 13
+	disconnectPacket = 14
+)
+
+// maxPacketSize limits packets read so a bad peer can't exhaust memory
+const maxPacketSize = 1 << 20
+
+// packet is a control packet with its fixed header's type and flags
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func (p packet) encode() []byte {
+	out := []byte{p.kind<<4 | p.flags}
+	length := len(p.body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		out = append(out, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(out, p.body...)
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	length := 0
+	for multiplier := 1; ; multiplier *= 128 {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if multiplier > 128*128 {
+			return packet{}, fmt.Errorf("malformed packet length")
+		}
+	}
+	if length > maxPacketSize {
+		return packet{}, fmt.Errorf("packet of %d bytes is too large", length)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+
+	return packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendUint16(b []byte, n uint16) []byte {
+	return append(b, byte(n>>8), byte(n))
+}
+
+// readString reads a length prefixed string from the start of b and returns
+// the rest of b
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, fmt.Errorf("truncated string")
+	}
+	length := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+length {
+		return "", nil, fmt.Errorf("truncated string")
+	}
+	return string(b[2 : 2+length]), b[2+length:], nil
+}
+
+// publish builds a QoS 0 PUBLISH packet for msg
+func (msg Message) publish() packet {
+	var flags byte
+	if msg.Retained {
+		flags = 1
+	}
+	body := appendString(nil, msg.Topic)
+	return packet{kind: publishPacket, flags: flags, body: append(body, msg.Payload...)}
+}
+
+// parsePublish reads a PUBLISH packet's message and packet identifier,
+// which is 0 for QoS 0
+func parsePublish(p packet) (Message, uint16, error) {
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return Message{}, 0, err
+	}
+
+	var id uint16
+	if qos := (p.flags >> 1) & 3; qos > 0 {
+		if len(rest) < 2 {
+			return Message{}, 0, fmt.Errorf("truncated publish")
+		}
+		id = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+
+	return Message{Topic: topic, Payload: rest, Retained: p.flags&1 != 0}, id, nil
+}
+
+// Match reports whether topic matches filter, which may use the + and #
+// wildcards
+func Match(filter, topic string) bool {
+	for {
+		filterLevel, filterRest, filterMore := cut(filter)
+		topicLevel, topicRest, topicMore := cut(topic)
+
+		switch {
+		case filterLevel == "#":
+			return true
+		case filterLevel != "+" && filterLevel != topicLevel:
+			return false
+		case !filterMore || !topicMore:
+			// a trailing /# also matches its parent level
+			return filterMore == topicMore || (filterMore && filterRest == "#")
+		}
+		filter, topic = filterRest, topicRest
+	}
+}
+
+func cut(s string) (before, after string, found bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '/' {
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/mqtt"
+)
+
+const mqttTestTimeout = 2 * time.Second
+
+// waitRetained waits for the broker to retain a message on topic which
+// satisfies ok
+func waitRetained(t *testing.T, broker *mqtt.Broker, topic string, ok func(mqtt.Message) bool) mqtt.Message {
+	t.Helper()
+	deadline := time.Now().Add(mqttTestTimeout)
+	for {
+		if msg, found := broker.Retained(topic); found && ok(msg) {
+			return msg
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("nothing suitable was retained on %s", topic)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func anyMessage(mqtt.Message) bool { return true }
+
+func TestMQTTBridge(t *testing.T) {
+	broker, err := mqtt.NewBroker("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+
+	actions := make(chan mediaAction, 1)
+	volumes := make(chan int, 1)
+	bridge := newMQTTBridge(broker.URL(), "test-player/", "homeassistant", commands{
+		out:     ioutil.Discard,
+		actions: actions,
+		volumes: volumes,
+	})
+	ended := make(chan error, 1)
+	go func() {
+		ended <- bridge.session()
+	}()
+
+	availability := waitRetained(t, broker, "test-player/availability", anyMessage)
+	if string(availability.Payload) != "online" {
+		t.Errorf("availability is %q, want online", availability.Payload)
+	}
+
+	// the state is published after subscribing to commands
+	state := waitRetained(t, broker, "test-player/state", anyMessage)
+	var published mqttState
+	if err := json.Unmarshal(state.Payload, &published); err != nil {
+		t.Fatal(err)
+	}
+	if published.Volume != fullVolume || published.Paused {
+		t.Errorf("initial state is %+v", published)
+	}
+
+	bridge.update(func(s *mqttState) {
+		s.Station = "WDWNTunes"
+		s.track(&models.TrackInfo{Title: "Main Street Electrical Parade", Artist: "Disneyland Band", Duration: 184.5})
+		s.Paused = true
+	})
+	waitRetained(t, broker, "test-player/state", func(msg mqtt.Message) bool {
+		var s mqttState
+		return json.Unmarshal(msg.Payload, &s) == nil &&
+			s.Station == "WDWNTunes" && s.Title == "Main Street Electrical Parade" && s.Paused
+	})
+
+	// commands published to the command topic run on the player
+	broker.Publish(mqtt.Message{Topic: "test-player/command", Payload: []byte("next")})
+	select {
+	case action := <-actions:
+		if action != nextMediaAction {
+			t.Errorf("ran action %v, want next", action)
+		}
+	case <-time.After(mqttTestTimeout):
+		t.Fatal("next command didn't run")
+	}
+	broker.Publish(mqtt.Message{Topic: "test-player/command", Payload: []byte("volume 40")})
+	select {
+	case volume := <-volumes:
+		if volume != 40 {
+			t.Errorf("set the volume to %d, want 40", volume)
+		}
+	case <-time.After(mqttTestTimeout):
+		t.Fatal("volume command didn't run")
+	}
+
+	broker.Close()
+	select {
+	case err := <-ended:
+		if err == nil {
+			t.Error("session ended without an error when the broker went away")
+		}
+	case <-time.After(mqttTestTimeout):
+		t.Fatal("session didn't end when the broker went away")
+	}
+}
+
+func TestMQTTDiscovery(t *testing.T) {
+	bridge := newMQTTBridge("tcp://localhost:1883", "stream-player", "homeassistant/", commands{})
+
+	configs := map[string]map[string]interface{}{}
+	for _, msg := range bridge.discoveryMessages("stream-player-den") {
+		if !msg.Retained {
+			t.Errorf("%s isn't retained", msg.Topic)
+		}
+		var config map[string]interface{}
+		if err := json.Unmarshal(msg.Payload, &config); err != nil {
+			t.Fatal(err)
+		}
+		if config["availability_topic"] != "stream-player/availability" {
+			t.Errorf("%s has availability topic %v", msg.Topic, config["availability_topic"])
+		}
+		configs[msg.Topic] = config
+	}
+
+	for topic, want := range map[string]map[string]interface{}{
+		"homeassistant/sensor/stream-player-den/track/config": {
+			"unique_id":   "stream-player-den_track",
+			"state_topic": "stream-player/state",
+		},
+		"homeassistant/number/stream-player-den/volume/config": {
+			"command_topic":    "stream-player/command",
+			"command_template": "volume {{ value }}",
+			"max":              float64(maxVolume),
+		},
+		"homeassistant/button/stream-player-den/next/config": {
+			"command_topic": "stream-player/command",
+			"payload_press": "next",
+		},
+	} {
+		config, ok := configs[topic]
+		if !ok {
+			t.Errorf("nothing announced on %s", topic)
+			continue
+		}
+		for key, value := range want {
+			if config[key] != value {
+				t.Errorf("%s: %s is %v, want %v", topic, key, config[key], value)
+			}
+		}
+	}
+}
+
+func TestMQTTCommandsRunInOrder(t *testing.T) {
+	broker, err := mqtt.NewBroker("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer broker.Close()
+
+	volumes := make(chan int)
+	bridge := newMQTTBridge(broker.URL(), "test-player", "", commands{
+		out:     ioutil.Discard,
+		volumes: volumes,
+	})
+	go bridge.session()
+	waitRetained(t, broker, "test-player/state", anyMessage)
+
+	// the main loop is slow to take each volume, so later commands queue up
+	for _, volume := range []string{"volume 10", "volume 20", "volume 30", "volume 40"} {
+		broker.Publish(mqtt.Message{Topic: "test-player/command", Payload: []byte(volume)})
+	}
+	for _, want := range []int{10, 20, 30, 40} {
+		select {
+		case volume := <-volumes:
+			if volume != want {
+				t.Errorf("set the volume to %d, want %d", volume, want)
+			}
+		case <-time.After(mqttTestTimeout):
+			t.Fatalf("volume %d command didn't run", want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMQTTStatePosition(t *testing.T) {
+	bridge := newMQTTBridge("tcp://localhost:1883", "stream-player", "", commands{})
+	bridge.update(func(s *mqttState) {
+		s.track(&models.TrackInfo{Title: "Grand Canyon Suite", Duration: 60, StartedAt: time.Now().Add(-2 * time.Minute)})
+	})
+
+	var state mqttState
+	if err := json.Unmarshal(bridge.stateMessage().Payload, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Position != 60 {
+		t.Errorf("position is %v, want it held at the duration of 60", state.Position)
+	}
+}
@@ -0,0 +1,220 @@
+package parks
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// Park is the name of a theme park
+type Park string
+
+// Known parks
+const (
+	MagicKingdom        Park = "Magic Kingdom"
+	EPCOT               Park = "EPCOT"
+	HollywoodStudios    Park = "Hollywood Studios"
+	AnimalKingdom       Park = "Animal Kingdom"
+	Disneyland          Park = "Disneyland"
+	CaliforniaAdventure Park = "California Adventure"
+	DisneySprings       Park = "Disney Springs"
+	TyphoonLagoon       Park = "Typhoon Lagoon"
+	BlizzardBeach       Park = "Blizzard Beach"
+	DisneylandParis     Park = "Disneyland Paris"
+	TokyoDisneyland     Park = "Tokyo Disneyland"
+	TokyoDisneySea      Park = "Tokyo DisneySea"
+)
+
+// Location is where in the parks a track is played
+type Location struct {
+	Park       Park
+	Land       string
+	Attraction string
+}
+
+type place struct {
+	name    string
+	park    Park
+	pattern *regexp.Regexp
+	exact   *regexp.Regexp
+}
+
+// newPlace matches name or any alias as whole words. Names like "U.S.A." end
+// in punctuation, so word boundaries are any non letter or digit.
+func newPlace(name string, park Park, aliases ...string) place {
+	names := append([]string{name}, aliases...)
+	for i, n := range names {
+		names[i] = regexp.QuoteMeta(n)
+	}
+	alternatives := `(?:` + strings.Join(names, "|") + `)`
+	return place{
+		name:    name,
+		park:    park,
+		pattern: regexp.MustCompile(`(?i)(?:^|[^\pL\pN])` + alternatives + `(?:[^\pL\pN]|$)`),
+		exact:   regexp.MustCompile(`(?i)^\s*` + alternatives + `\s*$`),
+	}
+}
+
+// parks are checked in order, so more specific names come before the names
+// they contain
+var parks = []place{
+	newPlace(string(TokyoDisneySea), TokyoDisneySea, "DisneySea", "Disney Sea"),
+	newPlace(string(TokyoDisneyland), TokyoDisneyland),
+	newPlace(string(DisneylandParis), DisneylandParis, "Disneyland Park Paris", "Euro Disney"),
+	newPlace(string(CaliforniaAdventure), CaliforniaAdventure, "Disney California Adventure", "DCA"),
+	newPlace(string(MagicKingdom), MagicKingdom, "MK"),
+	newPlace(string(EPCOT), EPCOT, "EPCOT Center"),
+	newPlace(string(HollywoodStudios), HollywoodStudios, "Disney-MGM Studios", "MGM Studios", "DHS"),
+	newPlace(string(AnimalKingdom), AnimalKingdom, "DAK"),
+	newPlace(string(DisneySprings), DisneySprings, "Downtown Disney"),
+	newPlace(string(TyphoonLagoon), TyphoonLagoon),
+	newPlace(string(BlizzardBeach), BlizzardBeach),
+	newPlace(string(Disneyland), Disneyland),
+}
+
+// lands with an empty park exist in more than one park
+var lands = []place{
+	newPlace("Main Street U.S.A.", "", "Main Street USA", "Main Street"),
+	newPlace("Adventureland", ""),
+	newPlace("Caribbean Plaza", MagicKingdom),
+	newPlace("Frontierland", ""),
+	newPlace("Liberty Square", MagicKingdom),
+	newPlace("Fantasyland", ""),
+	newPlace("Tomorrowland", ""),
+	newPlace("Storybook Circus", MagicKingdom),
+	newPlace("New Orleans Square", Disneyland),
+	newPlace("Critter Country", Disneyland),
+	newPlace("Toontown", Disneyland, "Mickey's Toontown"),
+	newPlace("World Showcase", EPCOT),
+	newPlace("Future World", EPCOT),
+	newPlace("World Celebration", EPCOT),
+	newPlace("World Discovery", EPCOT),
+	newPlace("World Nature", EPCOT),
+	newPlace("Showcase Plaza", EPCOT),
+	newPlace("Mexico Pavilion", EPCOT),
+	newPlace("Norway Pavilion", EPCOT),
+	newPlace("China Pavilion", EPCOT),
+	newPlace("Germany Pavilion", EPCOT),
+	newPlace("Italy Pavilion", EPCOT),
+	newPlace("American Adventure", EPCOT),
+	newPlace("Japan Pavilion", EPCOT),
+	newPlace("Morocco Pavilion", EPCOT),
+	newPlace("France Pavilion", EPCOT),
+	newPlace("United Kingdom Pavilion", EPCOT, "UK Pavilion"),
+	newPlace("Canada Pavilion", EPCOT),
+	newPlace("Hollywood Boulevard", HollywoodStudios),
+	newPlace("Sunset Boulevard", HollywoodStudios),
+	newPlace("Echo Lake", HollywoodStudios),
+	newPlace("Grand Avenue", HollywoodStudios),
+	newPlace("Toy Story Land", HollywoodStudios),
+	newPlace("Galaxy's Edge", "", "Star Wars: Galaxy's Edge"),
+	newPlace("Animation Courtyard", HollywoodStudios),
+	newPlace("Discovery Island", AnimalKingdom),
+	newPlace("Pandora", AnimalKingdom),
+	newPlace("Harambe", AnimalKingdom),
+	newPlace("Anandapur", AnimalKingdom),
+	newPlace("DinoLand U.S.A.", AnimalKingdom, "DinoLand USA", "DinoLand"),
+	newPlace("Buena Vista Street", CaliforniaAdventure),
+	newPlace("Pixar Pier", CaliforniaAdventure),
+	newPlace("Paradise Gardens", CaliforniaAdventure),
+	newPlace("Grizzly Peak", CaliforniaAdventure),
+	newPlace("Cars Land", CaliforniaAdventure),
+	newPlace("Avengers Campus", CaliforniaAdventure),
+	newPlace("Hollywood Land", CaliforniaAdventure),
+}
+
+// attractions with an empty park exist in more than one park
+var attractions = []place{
+	newPlace("Pirates of the Caribbean", ""),
+	newPlace("Haunted Mansion", ""),
+	newPlace("Jungle Cruise", ""),
+	newPlace("Enchanted Tiki Room", "", "Tiki Room"),
+	newPlace("Space Mountain", ""),
+	newPlace("Big Thunder Mountain", "", "Big Thunder"),
+	newPlace("Splash Mountain", ""),
+	newPlace("Country Bear Jamboree", MagicKingdom, "Country Bears"),
+	newPlace("Hall of Presidents", MagicKingdom),
+	newPlace("Carousel of Progress", MagicKingdom),
+	newPlace("PeopleMover", MagicKingdom, "People Mover"),
+	newPlace("Seven Dwarfs Mine Train", MagicKingdom),
+	newPlace("Tron Lightcycle Run", MagicKingdom, "TRON"),
+	newPlace("It's a Small World", "", "Small World"),
+	newPlace("Cinderella Castle", MagicKingdom),
+	newPlace("Sleeping Beauty Castle", Disneyland),
+	newPlace("Spaceship Earth", EPCOT),
+	newPlace("Test Track", EPCOT),
+	newPlace("Mission: Space", EPCOT, "Mission Space"),
+	newPlace("Soarin'", "", "Soarin"),
+	newPlace("Living with the Land", EPCOT),
+	newPlace("Journey into Imagination", EPCOT, "Imagination Pavilion"),
+	newPlace("Frozen Ever After", EPCOT),
+	newPlace("Guardians of the Galaxy: Cosmic Rewind", EPCOT, "Cosmic Rewind"),
+	newPlace("Remy's Ratatouille Adventure", EPCOT),
+	newPlace("The Seas with Nemo & Friends", EPCOT),
+	newPlace("Tower of Terror", "", "Twilight Zone Tower of Terror"),
+	newPlace("Rock 'n' Roller Coaster", HollywoodStudios, "Rock n Roller Coaster"),
+	newPlace("Rise of the Resistance", HollywoodStudios),
+	newPlace("Millennium Falcon: Smugglers Run", "", "Smugglers Run"),
+	newPlace("Slinky Dog Dash", HollywoodStudios),
+	newPlace("Expedition Everest", AnimalKingdom),
+	newPlace("Kilimanjaro Safaris", AnimalKingdom),
+	newPlace("Flight of Passage", AnimalKingdom, "Avatar Flight of Passage"),
+	newPlace("Na'vi River Journey", AnimalKingdom),
+	newPlace("Tree of Life", AnimalKingdom),
+	newPlace("Radiator Springs Racers", CaliforniaAdventure),
+	newPlace("Incredicoaster", CaliforniaAdventure),
+	newPlace("Indiana Jones Adventure", Disneyland),
+	newPlace("Matterhorn Bobsleds", Disneyland, "Matterhorn"),
+}
+
+// Classify finds the park, land and attraction named in a track's title,
+// artist and album. Fields not found are left empty.
+func Classify(info *models.TrackInfo) Location {
+	loc := Location{}
+	if info == nil {
+		return loc
+	}
+
+	text := strings.Join([]string{info.Title, info.Artist, info.Album}, " | ")
+
+	if p, ok := find(parks, text); ok {
+		loc.Park = p.park
+	}
+	if l, ok := find(lands, text); ok {
+		loc.Land = l.name
+		if loc.Park == "" {
+			loc.Park = l.park
+		}
+	}
+	if a, ok := find(attractions, text); ok {
+		loc.Attraction = a.name
+		if loc.Park == "" {
+			loc.Park = a.park
+		}
+	}
+
+	return loc
+}
+
+// Parse finds the park named by s, such as a command line flag, using the
+// same names and abbreviations recognized in track info
+func Parse(s string) (Park, bool) {
+	for _, p := range parks {
+		if p.exact.MatchString(s) {
+			return p.park, true
+		}
+	}
+
+	return "", false
+}
+
+func find(places []place, text string) (place, bool) {
+	for _, p := range places {
+		if p.pattern.MatchString(text) {
+			return p, true
+		}
+	}
+
+	return place{}, false
+}
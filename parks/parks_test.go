@@ -0,0 +1,133 @@
+package parks
+
+import (
+	"testing"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		info models.TrackInfo
+		want Location
+	}{
+		{
+			models.TrackInfo{Title: "Magic Kingdom Caribbean Plaza Area Loop pt1", Album: "Disney Parks", Artist: "Magic Kingdom"},
+			Location{Park: MagicKingdom, Land: "Caribbean Plaza"},
+		},
+		{
+			models.TrackInfo{Title: "Main Street U.S.A. Area Music", Artist: "Magic Kingdom"},
+			Location{Park: MagicKingdom, Land: "Main Street U.S.A."},
+		},
+		{
+			models.TrackInfo{Title: "Main Street Electrical Parade", Artist: "Disneyland Band"},
+			Location{Park: Disneyland, Land: "Main Street U.S.A."},
+		},
+		{
+			models.TrackInfo{Title: "Haunted Mansion Queue", Album: "Liberty Square"},
+			Location{Park: MagicKingdom, Land: "Liberty Square", Attraction: "Haunted Mansion"},
+		},
+		{
+			models.TrackInfo{Title: "Haunted Mansion Queue", Album: "New Orleans Square"},
+			Location{Park: Disneyland, Land: "New Orleans Square", Attraction: "Haunted Mansion"},
+		},
+		{
+			models.TrackInfo{Title: "Pirates of the Caribbean (Ride Through)"},
+			Location{Attraction: "Pirates of the Caribbean"},
+		},
+		{
+			models.TrackInfo{Title: "Walkin' Right Down the Middle of Main Street, U.S.A. / Tomorrowland Area", Album: "Disneyland"},
+			Location{Park: Disneyland, Land: "Main Street U.S.A."},
+		},
+		{
+			models.TrackInfo{Title: "World Showcase Entrance Loop", Artist: "EPCOT Center"},
+			Location{Park: EPCOT, Land: "World Showcase"},
+		},
+		{
+			models.TrackInfo{Title: "Spaceship Earth Post Show", Artist: "Epcot"},
+			Location{Park: EPCOT, Attraction: "Spaceship Earth"},
+		},
+		{
+			models.TrackInfo{Title: "Morocco Pavilion Background Music", Album: "EPCOT"},
+			Location{Park: EPCOT, Land: "Morocco Pavilion"},
+		},
+		{
+			models.TrackInfo{Title: "Hollywood Boulevard Area Music", Artist: "Disney-MGM Studios"},
+			Location{Park: HollywoodStudios, Land: "Hollywood Boulevard"},
+		},
+		{
+			models.TrackInfo{Title: "Twilight Zone Tower of Terror Queue"},
+			Location{Attraction: "Tower of Terror"},
+		},
+		{
+			models.TrackInfo{Title: "Pandora Loop", Album: "Disney's Animal Kingdom"},
+			Location{Park: AnimalKingdom, Land: "Pandora"},
+		},
+		{
+			models.TrackInfo{Title: "Expedition Everest Queue Music"},
+			Location{Park: AnimalKingdom, Attraction: "Expedition Everest"},
+		},
+		{
+			models.TrackInfo{Title: "Cars Land Area Loop", Album: "Disney California Adventure"},
+			Location{Park: CaliforniaAdventure, Land: "Cars Land"},
+		},
+		{
+			models.TrackInfo{Title: "Mediterranean Harbor", Album: "Tokyo DisneySea"},
+			Location{Park: TokyoDisneySea},
+		},
+		{
+			models.TrackInfo{Title: "Downtown Disney Marketplace Loop"},
+			Location{Park: DisneySprings},
+		},
+		// "MKT" isn't Magic Kingdom and "Epcotland" isn't EPCOT
+		{
+			models.TrackInfo{Title: "MKT Sessions", Artist: "Epcotland"},
+			Location{},
+		},
+		{
+			models.TrackInfo{Title: "Let It Go", Artist: "Idina Menzel", Album: "Frozen"},
+			Location{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.info.Title, func(t *testing.T) {
+			if got := Classify(&c.info); got != c.want {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestClassifyNil(t *testing.T) {
+	if got := Classify(nil); got != (Location{}) {
+		t.Errorf("got %+v for no track", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		s    string
+		want Park
+		ok   bool
+	}{
+		{"EPCOT", EPCOT, true},
+		{"epcot", EPCOT, true},
+		{" Magic Kingdom ", MagicKingdom, true},
+		{"MK", MagicKingdom, true},
+		{"DAK", AnimalKingdom, true},
+		{"Disney-MGM Studios", HollywoodStudios, true},
+		{"Disneyland", Disneyland, true},
+		{"Disneyland Paris", DisneylandParis, true},
+		{"DisneySea", TokyoDisneySea, true},
+		{"Magic Kingdom Park", "", false},
+		{"Adventureland", "", false},
+		{"", "", false},
+	}
+	for _, c := range cases {
+		got, ok := Parse(c.s)
+		if got != c.want || ok != c.ok {
+			t.Errorf("Parse(%q) is %q, %v, want %q, %v", c.s, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	vlc "github.com/adrg/libvlc-go/v3"
+	"github.com/codegoalie/stream-player/audio"
+	"github.com/codegoalie/stream-player/fade"
+)
+
+const (
+	// statsInterval is how often VLC's playback statistics are recorded
+	statsInterval = 5 * time.Second
+	// stallTimeout is how long a stream may go without sending anything
+	// before it's given up on
+	stallTimeout = 20 * time.Second
+)
+
+var (
+	errStreamEnded   = errors.New("stream ended")
+	errStreamFailed  = errors.New("VLC couldn't play the stream")
+	errStreamStalled = errors.New("stream stalled")
+)
+
+// playerOptions configures playAudio
+type playerOptions struct {
+	// buffer is how much audio VLC caches before playing
+	buffer time.Duration
+	// filters are VLC audio filter modules such as normvol or compressor
+	filters []string
+	// device is the audio output to start on, or nil for VLC's default
+	device *audio.Device
+	// crossfade is how long to fade between stations, or 0 to cut
+	crossfade time.Duration
+	// repeat loops streams which end, such as local files
+	repeat bool
+}
+
+// vlcPlayer is a VLC media player along with the media it's playing
+type vlcPlayer struct {
+	player   *vlc.Player
+	media    *vlc.Media
+	manager  *vlc.EventManager
+	eventIDs []vlc.EventID
+
+	// url is the stream being played, since playedAt
+	url      string
+	playedAt time.Time
+	// readBytes is how much of the stream VLC had read as of progressAt
+	readBytes  int
+	progressAt time.Time
+	// working is set once audio arrives and failed once the stream stops,
+	// so each is only reported once
+	working bool
+	failed  bool
+}
+
+// playerEvent is a stream stopping, from a VLC callback
+type playerEvent struct {
+	player *vlcPlayer
+	err    error
+	at     time.Time
+}
+
+// newVLCPlayer creates a player on device, or VLC's default device when nil,
+// which sends to events when its stream ends or fails. Events which can't be
+// sent straight away are dropped.
+func newVLCPlayer(device *audio.Device, events chan<- playerEvent) (*vlcPlayer, error) {
+	player, err := vlc.NewPlayer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new vlc player: %w", err)
+	}
+	p := &vlcPlayer{player: player}
+
+	// Retrieve player event manager.
+	p.manager, err = player.EventManager()
+	if err != nil {
+		p.release()
+		return nil, fmt.Errorf("failed to get vlc player event manager: %w", err)
+	}
+
+	// Register the media end reached and error events with the event
+	// manager.
+	eventCallback := func(event vlc.Event, userData interface{}) {
+		err := errStreamEnded
+		if event == vlc.MediaPlayerEncounteredError {
+			err = errStreamFailed
+		}
+		select {
+		case events <- playerEvent{player: p, err: err, at: time.Now()}:
+		default:
+		}
+	}
+	for _, event := range []vlc.Event{vlc.MediaPlayerEndReached, vlc.MediaPlayerEncounteredError} {
+		id, err := p.manager.Attach(event, eventCallback, nil)
+		if err != nil {
+			p.release()
+			return nil, fmt.Errorf("failed to attach to media player events: %w", err)
+		}
+		p.eventIDs = append(p.eventIDs, id)
+	}
+
+	if device != nil {
+		err = audio.SetDevice(player, device.Module, device.ID)
+		if err != nil {
+			p.release()
+			return nil, fmt.Errorf("failed to set audio device: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+// play replaces whatever p was playing with url
+func (p *vlcPlayer) play(url string) error {
+	if p.media != nil {
+		p.media.Release()
+	}
+
+	var err error
+	p.media, err = p.player.LoadMediaFromURL(url)
+	if err != nil {
+		return fmt.Errorf("failed to load media from url: %w", err)
+	}
+
+	p.url = url
+	p.playedAt = time.Now()
+	p.readBytes = 0
+	p.progressAt = p.playedAt
+	p.working = false
+	p.failed = false
+
+	// Start playing the media.
+	err = p.player.Play()
+	if err != nil {
+		return fmt.Errorf("failed to play media: %w", err)
+	}
+
+	return nil
+}
+
+// progress checks whether more of the stream has been read since last
+// time, returning a report the first time audio arrives or once the stream
+// has stalled
+func (p *vlcPlayer) progress(now time.Time) (streamReport, bool) {
+	if p.media == nil || p.failed {
+		return streamReport{}, false
+	}
+
+	stats, err := p.media.Stats()
+	if err != nil {
+		return streamReport{}, false
+	}
+	if stats.ReadBytes > p.readBytes {
+		p.readBytes = stats.ReadBytes
+		p.progressAt = now
+		if !p.working {
+			p.working = true
+			return streamReport{url: p.url}, true
+		}
+		return streamReport{}, false
+	}
+	if now.Sub(p.progressAt) >= stallTimeout {
+		p.failed = true
+		return streamReport{url: p.url, err: errStreamStalled}, true
+	}
+	return streamReport{}, false
+}
+
+// SetVolume sets the player's volume as a percent
+func (p *vlcPlayer) SetVolume(volume int) error {
+	return p.player.SetVolume(volume)
+}
+
+func (p *vlcPlayer) release() {
+	if p.manager != nil {
+		for _, id := range p.eventIDs {
+			p.manager.Detach(id)
+		}
+	}
+	p.player.Stop()
+	if p.media != nil {
+		p.media.Release()
+	}
+	p.player.Release()
+}
+
+// vlcArgs are the libvlc options for opts, to pass to vlc.Init
+func (opts playerOptions) vlcArgs() []string {
+	args := []string{
+		"--no-video",
+		"--quiet",
+		fmt.Sprintf("--network-caching=%d", opts.buffer.Milliseconds()),
+	}
+	if len(opts.filters) > 0 {
+		args = append(args, "--audio-filter="+strings.Join(opts.filters, ":"))
+	}
+	if opts.repeat {
+		args = append(args, "--input-repeat=65535")
+	}
+	return args
+}
+
+// crossfade is a fade from out to the current player running in the
+// background
+type crossfade struct {
+	out    *vlcPlayer
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// finish stops the fade if it's still running and releases the player faded
+// out
+func (c *crossfade) finish() {
+	c.cancel()
+	<-c.done
+	c.out.release()
+}
+
+// playAudio plays streams until quit, once vlc.Init has been called with
+// opts.vlcArgs(). volumes are set by rules and
+// masterVolumes by the listener, and both are percentages which are combined
+// along with each station's gain. Each stream is reported once, when audio
+// arrives or when it ends, fails or stalls first.
+func playAudio(opts playerOptions, streams <-chan stream, volumes, masterVolumes <-chan int, devices <-chan audio.Device, stops <-chan struct{}, reports chan<- streamReport, quit chan struct{}) {
+	events := make(chan playerEvent, 8)
+	report := func(r streamReport) {
+		// the main loop may be busy sending to this loop, so reports it
+		// isn't ready for are dropped rather than waited on
+		select {
+		case reports <- r:
+		default:
+		}
+	}
+
+	device := opts.device
+	current, err := newVLCPlayer(device, events)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		current.release()
+	}()
+
+	fader := fade.New(opts.crossfade)
+	var fading *crossfade
+	defer func() {
+		if fading != nil {
+			fading.finish()
+		}
+	}()
+
+	volume := fullVolume
+	master := fullVolume
+	var gain float64
+	playing := false
+	level := func() int {
+		return scaledVolume(volume*master/fullVolume, gain)
+	}
+
+	stats := time.NewTicker(statsInterval)
+	defer stats.Stop()
+
+play:
+	for {
+		// a nil channel blocks, so this case is skipped unless fading
+		var fadeDone chan struct{}
+		if fading != nil {
+			fadeDone = fading.done
+		}
+
+		select {
+		case next := <-streams:
+			gain = next.gain
+			target := level()
+
+			if opts.crossfade <= 0 || !playing || next.seek {
+				if err := current.play(next.url); err != nil {
+					log.Fatal(err)
+				}
+				playing = true
+				if err := current.SetVolume(target); err != nil {
+					log.Println("failed to set volume", err)
+				}
+				continue
+			}
+
+			if fading != nil {
+				fading.finish()
+				fading = nil
+			}
+
+			incoming, err := newVLCPlayer(device, events)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := incoming.SetVolume(0); err != nil {
+				log.Println("failed to set volume", err)
+			}
+			if err := incoming.play(next.url); err != nil {
+				log.Fatal(err)
+			}
+
+			from := level()
+			if v, err := current.player.Volume(); err == nil {
+				from = v
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			fading = &crossfade{out: current, cancel: cancel, done: make(chan struct{})}
+			current = incoming
+			go func(c *crossfade) {
+				defer close(c.done)
+				if err := fader.Crossfade(ctx, c.out, incoming, from, target); err != nil && err != context.Canceled {
+					log.Println("failed to crossfade", err)
+				}
+			}(fading)
+		case <-fadeDone:
+			fading.finish()
+			fading = nil
+			// the volume may have changed while fading
+			if err := current.SetVolume(level()); err != nil {
+				log.Println("failed to set volume", err)
+			}
+		case <-stats.C:
+			if playing && current.media != nil {
+				recordVLCStats(current.media)
+				if r, ok := current.progress(time.Now()); ok {
+					report(r)
+				}
+			}
+		case event := <-events:
+			// events from players faded out or media since replaced are
+			// stale
+			if event.player != current || event.at.Before(current.playedAt) || !playing || current.failed {
+				continue
+			}
+			current.failed = true
+			report(streamReport{url: current.url, err: event.err})
+		case <-stops:
+			// a fade still running would leave the outgoing station playing
+			if fading != nil {
+				fading.finish()
+				fading = nil
+			}
+			if err := current.player.Stop(); err != nil {
+				log.Println("failed to stop", err)
+			}
+			playing = false
+		case volume = <-volumes:
+			if fading != nil {
+				continue
+			}
+			if err := current.SetVolume(level()); err != nil {
+				log.Println("failed to set volume", err)
+			}
+		case master = <-masterVolumes:
+			if fading != nil {
+				continue
+			}
+			if err := current.SetVolume(level()); err != nil {
+				log.Println("failed to set volume", err)
+			}
+		case next := <-devices:
+			device = &next
+			players := []*vlcPlayer{current}
+			if fading != nil {
+				players = append(players, fading.out)
+			}
+			for _, p := range players {
+				if err := audio.SetDevice(p.player, device.Module, device.ID); err != nil {
+					log.Println("failed to set audio device", err)
+					continue
+				}
+				// a new output module only takes effect when playback restarts
+				if p.media != nil {
+					_ = p.player.Stop()
+					_ = p.player.Play()
+				}
+			}
+		case <-quit:
+			break play
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package relay
+
+import (
+	"io"
+	"strings"
+)
+
+// DefaultMetaInt is how many bytes of audio are sent between metadata
+// blocks, the same as Icecast's default
+const DefaultMetaInt = 16000
+
+// maxMetadata is the longest metadata block, whose length is sent as a
+// single byte counting 16 byte units
+const maxMetadata = 255 * 16
+
+// ICYWriter interleaves SHOUTcast style metadata blocks with the audio
+// written to it every metaInt bytes, sending the title whenever it changes
+type ICYWriter struct {
+	w       io.Writer
+	metaInt int
+	title   func() string
+
+	untilMeta int
+	sent      string
+	sentOnce  bool
+}
+
+// NewICYWriter writes audio to w with title's value as of each metadata
+// block
+func NewICYWriter(w io.Writer, metaInt int, title func() string) *ICYWriter {
+	return &ICYWriter{w: w, metaInt: metaInt, title: title, untilMeta: metaInt}
+}
+
+func (w *ICYWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > w.untilMeta {
+			n = w.untilMeta
+		}
+		n, err := w.w.Write(p[:n])
+		written += n
+		w.untilMeta -= n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+
+		if w.untilMeta == 0 {
+			if _, err := w.w.Write(w.metadata()); err != nil {
+				return written, err
+			}
+			w.untilMeta = w.metaInt
+		}
+	}
+	return written, nil
+}
+
+// metadata is the next metadata block, which is empty unless the title has
+// changed since the last one
+func (w *ICYWriter) metadata() []byte {
+	title := w.title()
+	if w.sentOnce && title == w.sent {
+		return []byte{0}
+	}
+	w.sent = title
+	w.sentOnce = true
+
+	return metadataBlock(title)
+}
+
+// metadataBlock encodes title as a StreamTitle metadata block, padded to a
+// multiple of 16 bytes after its length byte
+func metadataBlock(title string) []byte {
+	// a quote followed by a semicolon would end the title early
+	title = strings.Replace(title, "';", "'", -1)
+	meta := "StreamTitle='" + title + "';"
+	if len(meta) > maxMetadata {
+		meta = meta[:maxMetadata-2] + "';"
+	}
+
+	units := (len(meta) + 15) / 16
+	block := make([]byte, 1+units*16)
+	block[0] = byte(units)
+	copy(block[1:], meta)
+	return block
+}
@@ -0,0 +1,43 @@
+package relay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestICYWriter(t *testing.T) {
+	var out bytes.Buffer
+	title := "Disneyland Band - Main Street Electrical Parade"
+	w := NewICYWriter(&out, 4, func() string { return title })
+
+	if _, err := w.Write([]byte("abcdef")); err != nil {
+		t.Fatal(err)
+	}
+	title = "It's a Small World';"
+	if _, err := w.Write([]byte("ghijkl")); err != nil {
+		t.Fatal(err)
+	}
+
+	first := metadataBlock("Disneyland Band - Main Street Electrical Parade")
+	second := metadataBlock("It's a Small World'")
+	want := "abcd" + string(first) + "efgh" + string(second) + "ijkl" + "\x00"
+	if got := out.String(); got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+}
+
+func TestMetadataBlock(t *testing.T) {
+	block := metadataBlock("Grand Canyon Suite")
+	if len(block) != 1+int(block[0])*16 {
+		t.Fatalf("block is %d bytes but says %d units", len(block), block[0])
+	}
+	if got := strings.TrimRight(string(block[1:]), "\x00"); got != "StreamTitle='Grand Canyon Suite';" {
+		t.Errorf("block holds %q", got)
+	}
+
+	long := metadataBlock(strings.Repeat("x", 5000))
+	if long[0] != 255 || len(long) != 1+255*16 || !strings.HasSuffix(string(long), "';") {
+		t.Errorf("long title made a %d byte block", len(long))
+	}
+}
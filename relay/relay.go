@@ -0,0 +1,221 @@
+// Package relay rebroadcasts the station being played as an
+// Icecast-compatible stream so other players and devices can listen along.
+package relay
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// listenerBuffer is how many chunks a listener can fall behind before being
+// disconnected
+const listenerBuffer = 64
+
+// Server relays whichever station is current to any number of listeners at
+// a single URL which stays the same as stations change. The station's audio
+// is written to it as it's received, after calling Start.
+type Server struct {
+	// Name is sent to listeners as the stream's icy-name
+	Name string
+	// MetaInt is how many bytes of audio are sent between metadata blocks
+	// to listeners which ask for them
+	MetaInt int
+
+	listener net.Listener
+	server   *http.Server
+
+	mu          sync.Mutex
+	listeners   map[*listener]struct{}
+	title       string
+	contentType string
+}
+
+// listener is a connected client, which was told the stream is contentType
+type listener struct {
+	chunks      chan []byte
+	contentType string
+}
+
+// New starts relaying on addr, e.g. ":8000"
+func New(addr string) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		err = fmt.Errorf("failed to listen for relay: %w", err)
+		return nil, err
+	}
+
+	s := &Server{
+		Name:      "Stream Player",
+		MetaInt:   DefaultMetaInt,
+		listener:  l,
+		listeners: map[*listener]struct{}{},
+	}
+	s.server = &http.Server{Handler: s}
+	go s.server.Serve(l)
+
+	return s, nil
+}
+
+// URL is where listeners can tune in
+func (s *Server) URL() string {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	host := "localhost"
+	if !addr.IP.IsUnspecified() {
+		host = addr.IP.String()
+	}
+	return fmt.Sprintf("http://%s/", net.JoinHostPort(host, fmt.Sprint(addr.Port)))
+}
+
+// Start switches to relaying a new stream of contentType. Listeners of a
+// stream in a different format are disconnected, since their players can't
+// decode the new one, and can reconnect to hear it. A nil Server does
+// nothing.
+func (s *Server) Start(contentType string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.contentType = contentType
+	for l := range s.listeners {
+		if l.contentType != s.streamType() {
+			close(l.chunks)
+			delete(s.listeners, l)
+		}
+	}
+}
+
+// Write sends audio from the current stream to every listener. Listeners too
+// slow to keep up are disconnected. A nil Server discards the audio.
+func (s *Server) Write(audio []byte) (int, error) {
+	if s == nil {
+		return len(audio), nil
+	}
+
+	chunk := make([]byte, len(audio))
+	copy(chunk, audio)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for l := range s.listeners {
+		select {
+		case l.chunks <- chunk:
+		default:
+			close(l.chunks)
+			delete(s.listeners, l)
+		}
+	}
+
+	return len(audio), nil
+}
+
+// SetTrack sets the title sent in metadata to listeners from info. A nil
+// Server does nothing.
+func (s *Server) SetTrack(info *models.TrackInfo) {
+	if s == nil {
+		return
+	}
+
+	title := info.Title
+	if info.Artist != "" {
+		title = info.Artist + " - " + title
+	}
+
+	s.mu.Lock()
+	s.title = title
+	s.mu.Unlock()
+}
+
+// streamType is the content type sent to listeners, assuming MP3 until a
+// stream starts. s.mu must be held.
+func (s *Server) streamType() string {
+	if s.contentType == "" {
+		return "audio/mpeg"
+	}
+	return s.contentType
+}
+
+func (s *Server) currentTitle() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.title
+}
+
+// ServeHTTP streams the current stream to a listener until they disconnect
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	contentType := s.streamType()
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	w.Header().Set("icy-name", s.Name)
+	var out io.Writer = w
+	if strings.TrimSpace(r.Header.Get("Icy-MetaData")) == "1" && s.MetaInt > 0 {
+		w.Header().Set("icy-metaint", fmt.Sprint(s.MetaInt))
+		out = NewICYWriter(w, s.MetaInt, s.currentTitle)
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	// players wait for the headers before the first audio arrives
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	l := &listener{chunks: make(chan []byte, listenerBuffer), contentType: contentType}
+	s.mu.Lock()
+	if contentType != s.streamType() {
+		// the stream changed format while responding
+		s.mu.Unlock()
+		return
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.listeners[l]; ok {
+			delete(s.listeners, l)
+			close(l.chunks)
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case chunk, ok := <-l.chunks:
+			if !ok {
+				return
+			}
+			if _, err := out.Write(chunk); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Close disconnects every listener and stops relaying
+func (s *Server) Close() error {
+	return s.server.Close()
+}
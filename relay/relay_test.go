@@ -0,0 +1,94 @@
+package relay
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// connect tunes in to s, waiting until the listener is registered
+func connect(t *testing.T, s *Server) *http.Response {
+	t.Helper()
+
+	resp, err := http.Get(s.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		listeners := len(s.listeners)
+		s.mu.Unlock()
+		if listeners > 0 {
+			return resp
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("listener never registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerRelaysWrites(t *testing.T) {
+	s, err := New("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	s.Start("audio/aac")
+	resp := connect(t, s)
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "audio/aac" {
+		t.Errorf("content type %q, want audio/aac", got)
+	}
+
+	// a new stream in the same format carries on
+	s.Start("audio/aac")
+	if _, err := s.Write([]byte("audio")); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 5)
+	if _, err := io.ReadFull(resp.Body, got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "audio" {
+		t.Errorf("got %q, want audio", got)
+	}
+}
+
+func TestServerDisconnectsOnFormatChange(t *testing.T) {
+	s, err := New("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// listeners before any stream are assumed to want MP3
+	resp := connect(t, s)
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "audio/mpeg" {
+		t.Errorf("content type %q, want audio/mpeg", got)
+	}
+
+	s.Start("audio/aac")
+	s.Write([]byte("aac audio"))
+	rest, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("MP3 listener got %q", rest)
+	}
+}
+
+func TestNilServer(t *testing.T) {
+	var s *Server
+	s.Start("audio/mpeg")
+	if n, err := s.Write([]byte("audio")); n != 5 || err != nil {
+		t.Errorf("got %d, %v", n, err)
+	}
+}
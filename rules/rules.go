@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// Action is what to do while a blocked track is playing
+type Action string
+
+const (
+	// Skip switches to the next station until the blocked track ends
+	Skip Action = "skip"
+	// Mute silences the player until the blocked track ends
+	Mute Action = "mute"
+	// Lower turns the volume down until the blocked track ends
+	Lower Action = "lower"
+)
+
+// Rule blocks tracks whose fields match all of its non-empty patterns.
+// Patterns are regular expressions, so "(?i)" makes them case insensitive.
+type Rule struct {
+	Title     string `json:"title,omitempty"`
+	Artist    string `json:"artist,omitempty"`
+	Album     string `json:"album,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	Action    Action `json:"action"`
+	// Volume is the volume percentage used by the Lower action
+	Volume int `json:"volume,omitempty"`
+
+	title     *regexp.Regexp
+	artist    *regexp.Regexp
+	album     *regexp.Regexp
+	mediaType *regexp.Regexp
+}
+
+// Engine evaluates tracks against a list of rules
+type Engine struct {
+	rules []*Rule
+}
+
+// Load reads a JSON array of rules from path
+func Load(path string) (*Engine, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("failed to read rules file: %w", err)
+		return nil, err
+	}
+
+	rules := []*Rule{}
+	err = json.Unmarshal(raw, &rules)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal rules file: %w", err)
+		return nil, err
+	}
+
+	return New(rules...)
+}
+
+// New compiles rules into an Engine
+func New(rules ...*Rule) (*Engine, error) {
+	for i, rule := range rules {
+		switch rule.Action {
+		case Skip, Mute:
+		case Lower:
+			if rule.Volume < 0 || rule.Volume > 100 {
+				return nil, fmt.Errorf("rule %d: volume must be between 0 and 100", i)
+			}
+		default:
+			return nil, fmt.Errorf("rule %d: unknown action %q", i, rule.Action)
+		}
+
+		var err error
+		for _, pattern := range []struct {
+			src string
+			dst **regexp.Regexp
+		}{
+			{rule.Title, &rule.title},
+			{rule.Artist, &rule.artist},
+			{rule.Album, &rule.album},
+			{rule.MediaType, &rule.mediaType},
+		} {
+			if pattern.src == "" {
+				continue
+			}
+			*pattern.dst, err = regexp.Compile(pattern.src)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: failed to compile %q: %w", i, pattern.src, err)
+			}
+		}
+
+		if rule.title == nil && rule.artist == nil && rule.album == nil && rule.mediaType == nil {
+			return nil, fmt.Errorf("rule %d: at least one pattern is required", i)
+		}
+	}
+
+	return &Engine{rules: rules}, nil
+}
+
+// Match returns the first rule which blocks info or nil if it is allowed
+func (e *Engine) Match(info *models.TrackInfo) *Rule {
+	if e == nil || info == nil {
+		return nil
+	}
+
+	for _, rule := range e.rules {
+		if rule.matches(info) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+func (r *Rule) matches(info *models.TrackInfo) bool {
+	for _, check := range []struct {
+		pattern *regexp.Regexp
+		value   string
+	}{
+		{r.title, info.Title},
+		{r.artist, info.Artist},
+		{r.album, info.Album},
+		{r.mediaType, info.MediaType},
+	} {
+		if check.pattern != nil && !check.pattern.MatchString(check.value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	cases := []struct {
+		name string
+		rule Rule
+	}{
+		{"unknown action", Rule{Title: "ID", Action: "pause"}},
+		{"no action", Rule{Title: "ID"}},
+		{"no patterns", Rule{Action: Skip}},
+		{"bad pattern", Rule{Artist: "(", Action: Mute}},
+		{"volume too low", Rule{Title: "ID", Action: Lower, Volume: -1}},
+		{"volume too high", Rule{Title: "ID", Action: Lower, Volume: 101}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rule := c.rule
+			if _, err := New(&rule); err == nil {
+				t.Errorf("%+v was accepted", c.rule)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	// the rules from the README
+	engine, err := New(
+		&Rule{MediaType: "^(COM|NWS|INT)$", Action: Skip},
+		&Rule{Title: "(?i)station id", Action: Mute},
+		&Rule{Artist: "(?i)^dpark radio$", Action: Lower, Volume: 30},
+		&Rule{Title: "^Jingle$", Album: "^Holidays$", Action: Mute},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		info *models.TrackInfo
+		want Action
+	}{
+		{"music", &models.TrackInfo{Title: "Grand Canyon Suite", MediaType: "MUS"}, ""},
+		{"commercial", &models.TrackInfo{Title: "Visit Orlando", MediaType: "COM"}, Skip},
+		{"news", &models.TrackInfo{MediaType: "NWS"}, Skip},
+		{"interview", &models.TrackInfo{MediaType: "INT"}, Skip},
+		{"category is anchored", &models.TrackInfo{MediaType: "COMMUS"}, ""},
+		{"category is case sensitive", &models.TrackInfo{MediaType: "com"}, ""},
+		{"no category", &models.TrackInfo{Title: "Main Street Electrical Parade"}, ""},
+		{"case insensitive title", &models.TrackInfo{Title: "Sorcer Radio STATION ID", MediaType: "MUS"}, Mute},
+		{"artist", &models.TrackInfo{Title: "Promo", Artist: "DPark Radio"}, Lower},
+		{"artist is anchored", &models.TrackInfo{Artist: "DPark Radio Band"}, ""},
+		{"first match wins", &models.TrackInfo{Title: "Station ID", MediaType: "COM"}, Skip},
+		{"every pattern matches", &models.TrackInfo{Title: "Jingle", Album: "Holidays"}, Mute},
+		{"one pattern differs", &models.TrackInfo{Title: "Jingle", Album: "Parades"}, ""},
+		{"no track", nil, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got Action
+			if rule := engine.Match(c.info); rule != nil {
+				got = rule.Action
+			}
+			if got != c.want {
+				t.Errorf("matched %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMatchWithoutRules(t *testing.T) {
+	var engine *Engine
+	if rule := engine.Match(&models.TrackInfo{MediaType: "COM"}); rule != nil {
+		t.Errorf("a nil engine matched %+v", rule)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.json")
+	raw := `[
+		{"media_type": "^(COM|NWS|INT)$", "action": "skip"},
+		{"artist": "(?i)^dpark radio$", "action": "lower", "volume": 30}
+	]`
+	if err := ioutil.WriteFile(path, []byte(raw), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := engine.Match(&models.TrackInfo{Artist: "dpark radio"})
+	if rule == nil || rule.Action != Lower || rule.Volume != 30 {
+		t.Errorf("matched %+v, want lowering to 30", rule)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`[{"title": "ID"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("a truncated rules file loaded")
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("a missing rules file loaded")
+	}
+}
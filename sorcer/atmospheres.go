@@ -7,6 +7,10 @@ import (
 const atmospheresName = "Atmospheres (Sorcer Radio)"
 const atmospheresStreamURL = "https://samcloud.spacial.com/api/listen?sid=130157&m=sc&rid=273285"
 
+const atmospheresDescription = "Themed area loops and background music from the parks"
+const atmospheresHomepage = "http://srsounds.com/popperSRloops.php"
+const atmospheresGenre = "Theme Park Background Music"
+
 type Atmospheres struct{}
 
 // Name is the user presentable name for the stream
@@ -28,3 +32,20 @@ func (s Atmospheres) InfoURL() string {
 func (s Atmospheres) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
 	return parseTrackInfo(raw)
 }
+
+// ParseHistory parses the provided bytes into the recently played tracks
+func (s Atmospheres) ParseHistory(raw []byte) ([]*models.TrackInfo, error) {
+	return parseHistory(raw)
+}
+
+func (s Atmospheres) Description() string {
+	return atmospheresDescription
+}
+
+func (s Atmospheres) Homepage() string {
+	return atmospheresHomepage
+}
+
+func (s Atmospheres) Genre() string {
+	return atmospheresGenre
+}
@@ -0,0 +1,119 @@
+package sorcer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseError reports a field of a SAM track which couldn't be parsed. The
+// rest of the track is still returned alongside it.
+type ParseError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse Sorcer %s %q: %v", e.Field, e.Value, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errNoDurationPrefix = errors.New("duration must start with P")
+	errEmptyDuration    = errors.New("duration has no components")
+	errDurationNumber   = errors.New("duration component is missing its number")
+	errDurationUnit     = errors.New("duration component is missing its unit")
+	errDurationOrder    = errors.New("duration components are out of order")
+	errCalendarDuration = errors.New("years and months have no fixed length")
+	errDurationRange    = errors.New("duration is too long")
+)
+
+// durationUnits are the ISO-8601 designators in the order they must appear,
+// split by whether they come after the T separator
+var durationUnits = []struct {
+	designator byte
+	time       bool
+	length     time.Duration
+}{
+	{'W', false, 7 * 24 * time.Hour},
+	{'D', false, 24 * time.Hour},
+	{'H', true, time.Hour},
+	{'M', true, time.Minute},
+	{'S', true, time.Second},
+}
+
+// parseISODuration parses an ISO-8601 duration such as PT3M25.5S or P1DT2H.
+// Any component may have a fraction, with either a period or a comma.
+func parseISODuration(s string) (time.Duration, error) {
+	if !strings.HasPrefix(s, "P") {
+		return 0, errNoDurationPrefix
+	}
+
+	var total time.Duration
+	rest := s[1:]
+	inTime := false
+	next := 0
+	components := 0
+	for rest != "" {
+		if rest[0] == 'T' {
+			if inTime {
+				return 0, errDurationOrder
+			}
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+
+		end := strings.IndexFunc(rest, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != ','
+		})
+		if end == 0 {
+			return 0, errDurationNumber
+		}
+		if end < 0 {
+			return 0, errDurationUnit
+		}
+
+		number, err := strconv.ParseFloat(strings.Replace(rest[:end], ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration number %q: %w", rest[:end], err)
+		}
+
+		unit := rest[end]
+		rest = rest[end+1:]
+
+		found := false
+		for i := next; i < len(durationUnits); i++ {
+			if durationUnits[i].designator == unit && durationUnits[i].time == inTime {
+				length := number * float64(durationUnits[i].length)
+				if length+float64(total) >= math.MaxInt64 {
+					return 0, errDurationRange
+				}
+				total += time.Duration(length)
+				next = i + 1
+				found = true
+				break
+			}
+		}
+		if !found {
+			if !inTime && (unit == 'Y' || unit == 'M') {
+				return 0, errCalendarDuration
+			}
+			return 0, errDurationOrder
+		}
+		components++
+	}
+
+	if components == 0 {
+		return 0, errEmptyDuration
+	}
+
+	return total, nil
+}
@@ -0,0 +1,101 @@
+package sorcer
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  error
+	}{
+		{"PT1H", time.Hour, nil},
+		{"PT3M25.5S", 3*time.Minute + 25500*time.Millisecond, nil},
+		{"PT0,5S", 500 * time.Millisecond, nil},
+		{"P1DT2H", 26 * time.Hour, nil},
+		{"P1W", 7 * 24 * time.Hour, nil},
+		{"PT1H3M51S", time.Hour + 3*time.Minute + 51*time.Second, nil},
+		{"PT0S", 0, nil},
+		{"", 0, errNoDurationPrefix},
+		{"T1H", 0, errNoDurationPrefix},
+		{"P", 0, errEmptyDuration},
+		{"PT", 0, errEmptyDuration},
+		{"PTH", 0, errDurationNumber},
+		{"PT5", 0, errDurationUnit},
+		{"PT3S2M", 0, errDurationOrder},
+		{"PT1H1H", 0, errDurationOrder},
+		{"P1D1W", 0, errDurationOrder},
+		{"PT1HT2M", 0, errDurationOrder},
+		{"P2H", 0, errDurationOrder},
+		{"P1Y", 0, errCalendarDuration},
+		{"P2M", 0, errCalendarDuration},
+		{"P9999999W", 0, errDurationRange},
+		{"PT99999999999999999999S", 0, errDurationRange},
+	}
+
+	for _, test := range tests {
+		got, err := parseISODuration(test.in)
+		if !errors.Is(err, test.err) {
+			t.Errorf("parseISODuration(%q) error is %v, want %v", test.in, err, test.err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("parseISODuration(%q) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestParseISODurationMalformedNumber(t *testing.T) {
+	if _, err := parseISODuration("PT1.2.3S"); err == nil {
+		t.Error("PT1.2.3S parsed, want an error")
+	}
+}
+
+func TestSAMHistoryDurations(t *testing.T) {
+	payload, err := ioutil.ReadFile("testdata/sam_history.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := Atmospheres{}.ParseTrackInfo(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Duration != 3831 {
+		t.Errorf("current track lasts %v seconds, want 3831", info.Duration)
+	}
+
+	history, err := Atmospheres{}.ParseHistory(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{3831, 9.5, 205.574}
+	if len(history) != len(want) {
+		t.Fatalf("got %d tracks, want %d", len(history), len(want))
+	}
+	for i, seconds := range want {
+		if history[i].Duration != seconds {
+			t.Errorf("track %d lasts %v seconds, want %v", i, history[i].Duration, seconds)
+		}
+	}
+}
+
+func TestSAMHistoryBadDuration(t *testing.T) {
+	payload := []byte(`[{"Title":"Tiki Tiki Tiki Room","Artist":"Wally Boag","Album":"The Enchanted Tiki Room","Duration":"P1Y","DatePlayed":"\/Date(1628712685000+0000)\/","MediaTypeCode":"MUS"}]`)
+
+	info, err := Atmospheres{}.ParseTrackInfo(payload)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Field != "duration" {
+		t.Fatalf("error is %v, want a duration ParseError", err)
+	}
+	if !errors.Is(err, errCalendarDuration) {
+		t.Errorf("error is %v, want it to wrap %v", err, errCalendarDuration)
+	}
+	if info == nil || info.Title != "Tiki Tiki Tiki Room" || info.StartedAt.Unix() != 1628712685 {
+		t.Errorf("got %+v, want the rest of the track parsed", info)
+	}
+}
@@ -1,11 +1,9 @@
 package sorcer
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/url"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +25,7 @@ type sorcerRadioSong struct {
 	Duration    string `json:"Duration"`
 	DatePlayed  string `json:"DatePlayed"`
 	MediaItemID string `json:"MediaItemId"`
+	MediaType   string `json:"MediaTypeCode"`
 }
 
 func infoURL(stationID, token string) string {
@@ -43,112 +42,75 @@ func infoURL(stationID, token string) string {
 }
 
 func parseTrackInfo(raw []byte) (*models.TrackInfo, error) {
-	recentSongs := []sorcerRadioSong{}
-	err := json.Unmarshal(raw, &recentSongs)
+	recentSongs, err := unmarshalHistory(raw)
 	if err != nil {
-		err = fmt.Errorf(
-			"failed to unmarshal sorcer radio history: %w (%s)",
-			err,
-			string(raw),
-		)
 		return nil, err
 	}
 
-	info := &models.TrackInfo{}
-	if len(recentSongs) > 0 {
-		currentSong := recentSongs[0]
-		info.Title = currentSong.Title
-		info.Artist = currentSong.Artist
-		info.Album = currentSong.Album
-
-		durationRegexp := regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?([0-9\.]+)S$`)
-		matches := durationRegexp.FindAllStringSubmatch(currentSong.Duration, -1)
-
-		hours, err := strconv.Atoi(matches[0][1])
-		if err != nil {
-			hours = 0
-		}
-
-		minutes, err := strconv.Atoi(matches[0][2])
-		if err != nil {
-			minutes = 0
-		}
+	if len(recentSongs) == 0 {
+		return &models.TrackInfo{}, nil
+	}
 
-		seconds, err := strconv.ParseFloat(matches[0][3], 10)
-		if err != nil {
-			fmt.Println("failed to parse seconds", err)
-			seconds = 0
-		}
+	return recentSongs[0].trackInfo()
+}
 
-		info.Duration = float64(hours*60*60) + float64(minutes*60) + seconds
+func parseHistory(raw []byte) ([]*models.TrackInfo, error) {
+	recentSongs, err := unmarshalHistory(raw)
+	if err != nil {
+		return nil, err
+	}
 
-		unixStr := strings.Split(strings.Trim(currentSong.DatePlayed, "\\/Date()"), "+")[0]
-		unixMillisecs, err := strconv.ParseInt(unixStr, 10, 64)
-		if err != nil {
-			err = fmt.Errorf("failed to parse Sorcer started at: %w", err)
-			return info, err
+	var firstErr error
+	history := make([]*models.TrackInfo, 0, len(recentSongs))
+	for _, song := range recentSongs {
+		info, err := song.trackInfo()
+		if err != nil && firstErr == nil {
+			firstErr = err
 		}
-		startedAt := time.Unix(unixMillisecs/1000, 0)
-		info.StartedAt = startedAt
-
+		history = append(history, info)
 	}
 
-	return info, nil
+	return history, firstErr
 }
 
-func parseLive365TrackInfo(raw []byte) (*models.TrackInfo, error) {
-	resp := live365Response{}
-	err := json.Unmarshal(raw, &resp)
+func unmarshalHistory(raw []byte) ([]sorcerRadioSong, error) {
+	recentSongs := []sorcerRadioSong{}
+	err := json.Unmarshal(raw, &recentSongs)
 	if err != nil {
-		var printable bytes.Buffer
-		json.Indent(&printable, raw, "", "  ")
 		err = fmt.Errorf(
-			"failed to unmarshal sorcer radio history: %w\n%s",
+			"failed to unmarshal sorcer radio history: %w (%s)",
 			err,
-			printable.String(),
+			string(raw),
 		)
 		return nil, err
 	}
 
-	track := resp.CurrentTrack
-	info := models.TrackInfo{
-		Title:     track.Title,
-		Artist:    track.Artist,
-		Duration:  track.Duration,
-		StartedAt: time.Time(track.StartedAt),
-	}
-
-	return &info, nil
-
+	return recentSongs, nil
 }
 
-type live365Response struct {
-	CurrentTrack live365Song `json:"current-track"`
-}
-
-type live365Song struct {
-	Title     string      `json:"title"`
-	Artist    string      `json:"artist"`
-	Duration  float64     `json:"duration"`
-	StartedAt live365Time `json:"start"`
-
-	Art     string      `json:"art"`
-	EndedAt live365Time `json:"end"`
-	// SyncOffset string      `json:"sync_offset"`
-}
-
-type live365Time time.Time
+// trackInfo converts s into a TrackInfo. Fields which can't be parsed are
+// left empty and reported with a *ParseError.
+func (s sorcerRadioSong) trackInfo() (*models.TrackInfo, error) {
+	info := &models.TrackInfo{}
+	info.Title = s.Title
+	info.Artist = s.Artist
+	info.Album = s.Album
+	info.MediaType = s.MediaType
 
-func (t *live365Time) UnmarshalJSON(b []byte) error {
-	conv := strings.Replace(string(b), " ", "T", 1)
-	conv = strings.Trim(conv, `"`)
-	parsed, err := time.Parse(time.RFC3339Nano, conv)
+	var parseErr error
+	duration, err := parseISODuration(s.Duration)
+	if err != nil {
+		parseErr = &ParseError{Field: "duration", Value: s.Duration, Err: err}
+	}
+	info.Duration = duration.Seconds()
 
+	unixStr := strings.Split(strings.Trim(s.DatePlayed, "\\/Date()"), "+")[0]
+	unixMillisecs, err := strconv.ParseInt(unixStr, 10, 64)
 	if err != nil {
-		err = fmt.Errorf("failed to parse live365Time: %w", err)
-		return err
+		return info, &ParseError{Field: "started at", Value: s.DatePlayed, Err: err}
 	}
+	startedAt := time.Unix(unixMillisecs/1000, 0)
+	info.StartedAt = startedAt
 
-	*t = live365Time(parsed)
-	return nil
+	return info, parseErr
 }
@@ -1,6 +1,13 @@
 package sorcer
 
-import "github.com/codegoalie/stream-player/models"
+import (
+	"github.com/codegoalie/stream-player/live365"
+	"github.com/codegoalie/stream-player/models"
+)
+
+const mainDescription = "Disney music from the parks, films and beyond"
+const mainHomepage = "https://www.sorcerradio.com"
+const mainGenre = "Disney"
 
 type Main struct{}
 
@@ -17,5 +24,22 @@ func (m Main) InfoURL() string {
 }
 
 func (m Main) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
-	return parseLive365TrackInfo(raw)
+	return live365.ParseTrackInfo(raw)
+}
+
+// ParseHistory parses the provided bytes into the recently played tracks
+func (m Main) ParseHistory(raw []byte) ([]*models.TrackInfo, error) {
+	return live365.ParseHistory(raw)
+}
+
+func (m Main) Description() string {
+	return mainDescription
+}
+
+func (m Main) Homepage() string {
+	return mainHomepage
+}
+
+func (m Main) Genre() string {
+	return mainGenre
 }
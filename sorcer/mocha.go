@@ -7,6 +7,10 @@ import (
 const mochaName = "Mocha (Sorcer Radio)"
 const mochaStreamURL = "https://samcloud.spacial.com/api/listen?sid=100903&m=sc&rid=177361"
 
+const mochaDescription = "Relaxed Disney music for the coffee shop"
+const mochaHomepage = "https://www.sorcerradio.com"
+const mochaGenre = "Easy Listening"
+
 type Mocha struct{}
 
 // Name is the user presentable name for the stream
@@ -28,3 +32,20 @@ func (s Mocha) InfoURL() string {
 func (s Mocha) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
 	return parseTrackInfo(raw)
 }
+
+// ParseHistory parses the provided bytes into the recently played tracks
+func (s Mocha) ParseHistory(raw []byte) ([]*models.TrackInfo, error) {
+	return parseHistory(raw)
+}
+
+func (s Mocha) Description() string {
+	return mochaDescription
+}
+
+func (s Mocha) Homepage() string {
+	return mochaHomepage
+}
+
+func (s Mocha) Genre() string {
+	return mochaGenre
+}
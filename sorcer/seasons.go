@@ -7,6 +7,10 @@ import (
 const seasonsName = "Seasons (Sorcer Radio)"
 const seasonsStreamURL = "http://19293.live.streamtheworld.com/SP_R2809833"
 
+const seasonsDescription = "Seasonal and holiday music from the parks"
+const seasonsHomepage = "https://www.sorcerradio.com"
+const seasonsGenre = "Holiday"
+
 type Seasons struct{}
 
 // Name is the user presentable name for the stream
@@ -28,3 +32,20 @@ func (s Seasons) InfoURL() string {
 func (s Seasons) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
 	return parseTrackInfo(raw)
 }
+
+// ParseHistory parses the provided bytes into the recently played tracks
+func (s Seasons) ParseHistory(raw []byte) ([]*models.TrackInfo, error) {
+	return parseHistory(raw)
+}
+
+func (s Seasons) Description() string {
+	return seasonsDescription
+}
+
+func (s Seasons) Homepage() string {
+	return seasonsHomepage
+}
+
+func (s Seasons) Genre() string {
+	return seasonsGenre
+}
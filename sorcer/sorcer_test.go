@@ -0,0 +1,40 @@
+package sorcer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/sourcetest"
+)
+
+func samGolden(t *testing.T) sourcetest.Golden {
+	return sourcetest.ReadGolden(t, "SAM Cloud history", "testdata/sam_history.json", models.TrackInfo{
+		Title:     "Magic Kingdom Caribbean Plaza Area Loop pt1",
+		Album:     "Disney Parks",
+		Artist:    "Magic Kingdom",
+		Duration:  3831,
+		StartedAt: time.Unix(1628712901, 0),
+		MediaType: "MUS",
+	})
+}
+
+func TestSAMStations(t *testing.T) {
+	for _, source := range []models.MediaSource{Atmospheres{}, Seasons{}, Mocha{}} {
+		t.Run(source.Name(), func(t *testing.T) {
+			sourcetest.Run(t, source, sourcetest.Options{
+				CacheBusting: true,
+				Golden:       []sourcetest.Golden{samGolden(t)},
+			})
+		})
+	}
+}
+
+// Payloads are parsed by the live365 package, which is tested there
+func TestLive365Stations(t *testing.T) {
+	for _, source := range []models.MediaSource{Main{}, SpaDay{}} {
+		t.Run(source.Name(), func(t *testing.T) {
+			sourcetest.Run(t, source, sourcetest.Options{})
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package sorcer
 
 import (
+	"github.com/codegoalie/stream-player/live365"
 	"github.com/codegoalie/stream-player/models"
 )
 
@@ -8,6 +9,10 @@ const streamName = "Spa Day (Sorcer Radio)"
 const spaStreamURL = "https://streaming.live365.com/a88328"
 const spaHistoryURL = "https://api.live365.com/station/a88328"
 
+const spaDayDescription = "Calming instrumental Disney music"
+const spaDayHomepage = "https://www.sorcerradio.com"
+const spaDayGenre = "Relaxation"
+
 type SpaDay struct{}
 
 func (s SpaDay) Name() string {
@@ -31,5 +36,22 @@ func (s SpaDay) InfoURL() string {
 // }
 
 func (s SpaDay) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
-	return parseLive365TrackInfo(raw)
+	return live365.ParseTrackInfo(raw)
+}
+
+// ParseHistory parses the provided bytes into the recently played tracks
+func (s SpaDay) ParseHistory(raw []byte) ([]*models.TrackInfo, error) {
+	return live365.ParseHistory(raw)
+}
+
+func (s SpaDay) Description() string {
+	return spaDayDescription
+}
+
+func (s SpaDay) Homepage() string {
+	return spaDayHomepage
+}
+
+func (s SpaDay) Genre() string {
+	return spaDayGenre
 }
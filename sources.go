@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/codegoalie/stream-player/utils"
+)
+
+var errEmptyMetadata = errors.New("metadata response was empty")
+
+// fetchTrackInfo fetches and parses the current track of fetcher, letting
+// sources which implement models.ContextFetcher fetch it themselves
+func fetchTrackInfo(ctx context.Context, fetcher models.InfoFetcher) (*models.TrackInfo, error) {
+	info, _, err := fetchMetadata(ctx, fetcher)
+	return info, err
+}
+
+// fetchMetadata fetches the current track of fetcher along with its recently
+// played tracks, most recent first, for sources which implement
+// models.HistoryProvider. history is nil for all other sources. When only
+// part of the payload can be parsed, info is returned along with the error.
+func fetchMetadata(ctx context.Context, fetcher models.InfoFetcher) (info *models.TrackInfo, history []*models.TrackInfo, err error) {
+	infoURL := fetcher.InfoURL()
+	var body []byte
+	defer func() {
+		recordFetch(fetcher.Name(), infoURL, err)
+		if err != nil {
+			saveFailure(fetcher.Name(), infoURL, body, err)
+		}
+	}()
+
+	if contextFetcher, ok := fetcher.(models.ContextFetcher); ok {
+		info, err = contextFetcher.FetchTrackInfo(ctx)
+		if info == nil && err == nil {
+			err = errEmptyMetadata
+		}
+		return info, nil, err
+	}
+
+	buf, err := utils.DefaultClient.Get(ctx, infoURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	body = buf.Bytes()
+
+	if buf.Len() == 0 {
+		return nil, nil, errEmptyMetadata
+	}
+
+	info, err = fetcher.ParseTrackInfo(body)
+	if info == nil {
+		if err == nil {
+			err = errEmptyMetadata
+		}
+		return nil, nil, err
+	}
+
+	if provider, ok := fetcher.(models.HistoryProvider); ok {
+		// history is extra, so a payload it can't parse still shows the
+		// current track
+		history, _ = provider.ParseHistory(body)
+	}
+
+	return info, history, err
+}
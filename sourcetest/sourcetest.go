@@ -0,0 +1,297 @@
+// Package sourcetest checks that a models.MediaSource behaves the way the
+// player expects of every station. Call Run from a test with *testing.T, or
+// from anything else with a Reporter of its own.
+package sourcetest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// maxBusterAge is how far a cache busting timestamp may be from now
+const maxBusterAge = 24 * time.Hour
+
+// Reporter is told about each check which fails. *testing.T satisfies it.
+type Reporter interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Golden is a payload recorded from a station's InfoURL along with the
+// TrackInfo it should parse into
+type Golden struct {
+	Name    string
+	Payload []byte
+	Want    models.TrackInfo
+}
+
+// ReadGolden loads a Golden's payload from path, failing the test when it
+// can't be read
+func ReadGolden(t testing.TB, name, path string, want models.TrackInfo) Golden {
+	t.Helper()
+	payload, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Golden{Name: name, Payload: payload, Want: want}
+}
+
+// Options describe what to expect of a source beyond what every source
+// must do
+type Options struct {
+	// CacheBusting is true when InfoURL adds the current time as an "_"
+	// query parameter so responses aren't cached along the way
+	CacheBusting bool
+	// Golden payloads must parse into exactly their TrackInfo
+	Golden []Golden
+}
+
+// garbage are payloads no station sends, which must be refused or parsed
+// without panicking
+var garbage = [][]byte{
+	nil,
+	[]byte(""),
+	[]byte("null"),
+	[]byte("{}"),
+	[]byte("[]"),
+	[]byte("[null]"),
+	[]byte(`{"error":"station not found"}`),
+	[]byte("<html><body>502 Bad Gateway</body></html>"),
+	[]byte("\x00\xff\xfe{["),
+	[]byte(strings.Repeat("[", 10000)),
+}
+
+// Run makes every check of source
+func Run(r Reporter, source models.MediaSource, opts Options) {
+	r.Helper()
+	CheckName(r, source)
+	CheckURLs(r, source)
+	CheckInfoURL(r, source, opts.CacheBusting)
+	CheckPayloads(r, source, opts.Golden)
+	for _, golden := range opts.Golden {
+		CheckGolden(r, source, golden)
+	}
+}
+
+// CheckName makes sure Name is non-empty, trimmed and the same every time
+func CheckName(r Reporter, source models.MediaSource) {
+	r.Helper()
+	name := source.Name()
+	if strings.TrimSpace(name) == "" {
+		r.Errorf("Name is empty")
+		return
+	}
+	if strings.TrimSpace(name) != name {
+		r.Errorf("Name %q has leading or trailing space", name)
+	}
+	if again := source.Name(); again != name {
+		r.Errorf("Name changed from %q to %q", name, again)
+	}
+}
+
+// CheckURLs makes sure StreamURL, InfoURL and any mirrors are absolute HTTP
+// URLs
+func CheckURLs(r Reporter, source models.MediaSource) {
+	r.Helper()
+	checkURL(r, "StreamURL", source.StreamURL())
+	checkURL(r, "InfoURL", source.InfoURL())
+
+	mirrors, ok := source.(models.MirrorProvider)
+	if !ok {
+		return
+	}
+	urls := mirrors.StreamURLs()
+	if len(urls) == 0 {
+		r.Errorf("StreamURLs is empty")
+		return
+	}
+	if urls[0] != source.StreamURL() {
+		r.Errorf("StreamURLs starts with %q instead of StreamURL %q", urls[0], source.StreamURL())
+	}
+	for i, u := range urls {
+		checkURL(r, fmt.Sprintf("StreamURLs[%d]", i), u)
+	}
+}
+
+func checkURL(r Reporter, method, rawURL string) {
+	r.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		r.Errorf("%s %q doesn't parse: %v", method, rawURL, err)
+		return
+	}
+	if !u.IsAbs() || u.Host == "" {
+		r.Errorf("%s %q isn't absolute", method, rawURL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		r.Errorf("%s %q isn't HTTP", method, rawURL)
+	}
+}
+
+// CheckInfoURL makes sure InfoURL only changes in its "_" query parameter,
+// and when cacheBusting that the parameter is the current time
+func CheckInfoURL(r Reporter, source models.MediaSource, cacheBusting bool) {
+	r.Helper()
+	first, err := url.Parse(source.InfoURL())
+	if err != nil {
+		// reported by CheckURLs
+		return
+	}
+	second, err := url.Parse(source.InfoURL())
+	if err != nil {
+		r.Errorf("InfoURL %q doesn't parse the second time: %v", source.InfoURL(), err)
+		return
+	}
+
+	firstBuster, firstRest := splitBuster(first)
+	_, secondRest := splitBuster(second)
+	if firstRest != secondRest {
+		r.Errorf("InfoURL changed from %q to %q beyond its \"_\" parameter", first, second)
+	}
+
+	if !cacheBusting {
+		return
+	}
+	if firstBuster == "" {
+		r.Errorf("InfoURL %q has no \"_\" parameter to bust caches", first)
+		return
+	}
+	at, ok := parseTimestamp(firstBuster)
+	if !ok {
+		r.Errorf("InfoURL %q has a \"_\" parameter which isn't a timestamp", first)
+		return
+	}
+	if age := time.Since(at); age > maxBusterAge || age < -maxBusterAge {
+		r.Errorf("InfoURL %q busts caches with %s, not the current time", first, at.Format(time.RFC3339))
+	}
+}
+
+// splitBuster separates u's "_" query parameter from the rest of it
+func splitBuster(u *url.URL) (string, string) {
+	query := u.Query()
+	buster := query.Get("_")
+	query.Del("_")
+	rest := *u
+	rest.RawQuery = query.Encode()
+	return buster, rest.String()
+}
+
+// parseTimestamp reads a Unix time in seconds or milliseconds
+func parseTimestamp(s string) (time.Time, bool) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+	if n > 1e11 {
+		return time.Unix(0, n*int64(time.Millisecond)), true
+	}
+	return time.Unix(n, 0), true
+}
+
+// CheckPayloads feeds ParseTrackInfo, and ParseHistory when source has it,
+// empty and garbage payloads along with truncated copies of goldens. Each
+// must be refused with an error or parsed, never panic.
+func CheckPayloads(r Reporter, source models.MediaSource, goldens []Golden) {
+	r.Helper()
+	payloads := append([][]byte{}, garbage...)
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 5; i++ {
+		noise := make([]byte, 64+random.Intn(512))
+		random.Read(noise)
+		payloads = append(payloads, noise)
+	}
+	for _, golden := range goldens {
+		for _, n := range []int{1, len(golden.Payload) / 4, len(golden.Payload) / 2, len(golden.Payload) - 1} {
+			if n > 0 && n < len(golden.Payload) {
+				payloads = append(payloads, golden.Payload[:n])
+			}
+		}
+	}
+
+	for _, payload := range payloads {
+		checkPayload(r, source, payload)
+	}
+}
+
+func checkPayload(r Reporter, source models.MediaSource, payload []byte) {
+	r.Helper()
+	defer func() {
+		if p := recover(); p != nil {
+			r.Errorf("parsing %s panicked: %v", describe(payload), p)
+		}
+	}()
+
+	info, err := source.ParseTrackInfo(payload)
+	if err == nil && info == nil {
+		r.Errorf("ParseTrackInfo returned neither a TrackInfo nor an error for %s", describe(payload))
+	}
+
+	if history, ok := source.(models.HistoryProvider); ok {
+		tracks, err := history.ParseHistory(payload)
+		for i, track := range tracks {
+			if track == nil && err == nil {
+				r.Errorf("ParseHistory returned a nil track %d for %s", i, describe(payload))
+			}
+		}
+	}
+}
+
+// describe summarizes a payload for a failure message
+func describe(payload []byte) string {
+	const max = 40
+	if len(payload) == 0 {
+		return "an empty payload"
+	}
+	if len(payload) > max {
+		return fmt.Sprintf("%q... (%d bytes)", payload[:max], len(payload))
+	}
+	return fmt.Sprintf("%q", payload)
+}
+
+// CheckGolden makes sure golden's payload parses into exactly its TrackInfo
+func CheckGolden(r Reporter, source models.MediaSource, golden Golden) {
+	r.Helper()
+	got, err := source.ParseTrackInfo(golden.Payload)
+	if err != nil {
+		r.Errorf("golden %s failed to parse: %v", golden.Name, err)
+		return
+	}
+	if got == nil {
+		r.Errorf("golden %s parsed into nothing", golden.Name)
+		return
+	}
+	for _, difference := range Diff(got, &golden.Want) {
+		r.Errorf("golden %s: %s", golden.Name, difference)
+	}
+}
+
+// Diff lists the fields parsed from a payload which differ between got and
+// want. Fields filled in later, like ArtPath, are ignored.
+func Diff(got, want *models.TrackInfo) []string {
+	differences := []string{}
+	compare := func(field string, got, want interface{}) {
+		if got != want {
+			differences = append(differences, fmt.Sprintf("%s is %#v, want %#v", field, got, want))
+		}
+	}
+	compare("Title", got.Title, want.Title)
+	compare("Album", got.Album, want.Album)
+	compare("Artist", got.Artist, want.Artist)
+	compare("Duration", got.Duration, want.Duration)
+	compare("DurationEstimated", got.DurationEstimated, want.DurationEstimated)
+	compare("MediaType", got.MediaType, want.MediaType)
+	compare("SyncOffset", got.SyncOffset, want.SyncOffset)
+	compare("ArtURL", got.ArtURL, want.ArtURL)
+	if !got.StartedAt.Equal(want.StartedAt) {
+		differences = append(differences, fmt.Sprintf("StartedAt is %s, want %s", got.StartedAt, want.StartedAt))
+	}
+	return differences
+}
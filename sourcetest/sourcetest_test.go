@@ -0,0 +1,127 @@
+package sourcetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/codegoalie/stream-player/models"
+)
+
+// reporter collects failures instead of failing the test
+type reporter struct {
+	failures []string
+}
+
+func (r *reporter) Helper() {}
+
+func (r *reporter) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// reported tells whether a failure mentioning substr was reported
+func (r *reporter) reported(substr string) bool {
+	for _, failure := range r.failures {
+		if strings.Contains(failure, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+type source struct {
+	name      string
+	streamURL string
+	infoURL   string
+	parse     func([]byte) (*models.TrackInfo, error)
+}
+
+func (s source) Name() string      { return s.name }
+func (s source) StreamURL() string { return s.streamURL }
+func (s source) InfoURL() string   { return s.infoURL }
+
+func (s source) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
+	return s.parse(raw)
+}
+
+func parseTitle(raw []byte) (*models.TrackInfo, error) {
+	return &models.TrackInfo{Title: string(raw)}, nil
+}
+
+var golden = Golden{
+	Name:    "title",
+	Payload: []byte("Grand Canyon Suite"),
+	Want:    models.TrackInfo{Title: "Grand Canyon Suite"},
+}
+
+func TestRunPasses(t *testing.T) {
+	r := &reporter{}
+	Run(r, source{
+		name:      "Test Station",
+		streamURL: "https://example.com/stream",
+		infoURL:   "https://example.com/info",
+		parse:     parseTitle,
+	}, Options{Golden: []Golden{golden}})
+
+	if len(r.failures) != 0 {
+		t.Errorf("a working source failed: %q", r.failures)
+	}
+}
+
+func TestRunReportsBrokenSources(t *testing.T) {
+	cases := []struct {
+		name   string
+		source source
+		opts   Options
+		want   string
+	}{
+		{
+			name:   "blank name",
+			source: source{name: " ", streamURL: "https://example.com/stream", infoURL: "https://example.com/info", parse: parseTitle},
+			want:   "Name is empty",
+		},
+		{
+			name:   "relative stream",
+			source: source{name: "Test Station", streamURL: "/stream", infoURL: "https://example.com/info", parse: parseTitle},
+			want:   `StreamURL "/stream" isn't absolute`,
+		},
+		{
+			name:   "no cache buster",
+			source: source{name: "Test Station", streamURL: "https://example.com/stream", infoURL: "https://example.com/info", parse: parseTitle},
+			opts:   Options{CacheBusting: true},
+			want:   "has no \"_\" parameter",
+		},
+		{
+			name: "panics on garbage",
+			source: source{name: "Test Station", streamURL: "https://example.com/stream", infoURL: "https://example.com/info", parse: func(raw []byte) (*models.TrackInfo, error) {
+				return &models.TrackInfo{Title: string(raw[:1])}, nil
+			}},
+			want: "parsing an empty payload panicked",
+		},
+		{
+			name: "nothing parsed",
+			source: source{name: "Test Station", streamURL: "https://example.com/stream", infoURL: "https://example.com/info", parse: func([]byte) (*models.TrackInfo, error) {
+				return nil, nil
+			}},
+			want: "returned neither a TrackInfo nor an error",
+		},
+		{
+			name: "wrong golden",
+			source: source{name: "Test Station", streamURL: "https://example.com/stream", infoURL: "https://example.com/info", parse: func([]byte) (*models.TrackInfo, error) {
+				return &models.TrackInfo{Title: "Station ID"}, nil
+			}},
+			opts: Options{Golden: []Golden{golden}},
+			want: `golden title: Title is "Station ID", want "Grand Canyon Suite"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := &reporter{}
+			Run(r, c.source, c.opts)
+			if !r.reported(c.want) {
+				t.Errorf("didn't report %q, reported %q", c.want, r.failures)
+			}
+		})
+	}
+}
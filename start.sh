@@ -3,4 +3,4 @@
 set -e
 
 export PKG_CONFIG_PATH=/usr/lib/x86_64-linux-gnu/pkgconfig/:/usr/share/pkgconfig/
-go run main.go
+go run .
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codegoalie/stream-player/dpark"
+)
+
+// templateFlags collects -dpark-template values of the form
+// station=template, where station is any prefix of a DPark Radio station name
+// and template is a named template or regular expression
+type templateFlags map[string][]*dpark.Template
+
+func (f templateFlags) String() string {
+	parts := []string{}
+	for name, templates := range f {
+		for _, t := range templates {
+			parts = append(parts, name+"="+t.String())
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f templateFlags) Set(value string) error {
+	split := strings.SplitN(value, "=", 2)
+	if len(split) != 2 {
+		return fmt.Errorf("expected station=template, got %q", value)
+	}
+
+	name, err := dparkStationName(split[0])
+	if err != nil {
+		return err
+	}
+
+	template, err := dpark.ParseTemplate(split[1])
+	if err != nil {
+		return err
+	}
+
+	f[name] = append(f[name], template)
+	return nil
+}
+
+// apply configures each station's templates in the order they were given
+func (f templateFlags) apply() {
+	for name, templates := range f {
+		dpark.SetTemplates(name, templates...)
+	}
+}
+
+func dparkStationName(prefix string) (string, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	for _, name := range dpark.StationNames() {
+		if prefix != "" && strings.HasPrefix(strings.ToLower(name), prefix) {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown DPark Radio station %q", prefix)
+}
@@ -0,0 +1,427 @@
+// Package timeshift buffers live streams so they can be paused, rewound and
+// caught up with like a recording.
+package timeshift
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const chunkSize = 32 * 1024
+
+// PassThroughSize is enough buffer to play streams live through a Proxy,
+// e.g. to Tap them, without keeping much to rewind
+const PassThroughSize = 1 << 20
+
+// Tap is sent a copy of the current stream as it's captured, such as to
+// relay it, so nothing else needs its own connection to the station
+type Tap interface {
+	// Start is called as each stream starts with its content type
+	Start(contentType string)
+	io.Writer
+}
+
+// Proxy captures a stream into a bounded buffer and serves it to the player
+// over HTTP from wherever the listener has paused or rewound to
+type Proxy struct {
+	// Tap, if set, is sent the live stream whether or not it's being
+	// played, paused or rewound
+	Tap Tap
+
+	size int64
+	dir  string
+
+	client   *http.Client
+	listener net.Listener
+	server   *http.Server
+
+	mu         sync.Mutex
+	generation int
+	captures   map[int]*capture
+	current    *capture
+	// spare is the storage of a released capture, kept for the next one so
+	// changing stations doesn't allocate a new buffer each time
+	spare    storage
+	behind   time.Duration
+	pausedAt time.Time
+}
+
+// capture is one stream being buffered. A capture replaced by a newer one
+// keeps recording until the last player reading it lets go, e.g. after a
+// crossfade.
+type capture struct {
+	ring        *ring
+	contentType string
+	cancel      context.CancelFunc
+
+	// updated is closed and replaced whenever audio is written or capturing
+	// stops
+	updated  chan struct{}
+	stopped  bool
+	err      error
+	readers  int
+	replaced bool
+	released bool
+}
+
+// New starts a proxy on localhost which keeps the last size bytes of audio
+// in memory, or in a temporary file in dir when dir isn't empty
+func New(size int64, dir string) (*Proxy, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("timeshift buffer size must be positive, got %d", size)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		err = fmt.Errorf("failed to listen for timeshift proxy: %w", err)
+		return nil, err
+	}
+
+	p := &Proxy{
+		size: size,
+		dir:  dir,
+		// streams never finish, so only connecting is timed out
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: 10 * time.Second}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 10 * time.Second,
+			},
+		},
+		listener: listener,
+		captures: map[int]*capture{},
+	}
+	p.server = &http.Server{Handler: p}
+	go p.server.Serve(listener)
+
+	return p, nil
+}
+
+// Capture starts buffering streamURL, replacing the previous stream, and
+// returns the local URL to play it from. Playback starts live.
+func (p *Proxy) Capture(streamURL string) (string, error) {
+	p.mu.Lock()
+	store := p.spare
+	p.spare = nil
+	p.mu.Unlock()
+	if store == nil {
+		var err error
+		store, err = p.newStorage()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &capture{
+		ring:    newRing(store, p.size),
+		cancel:  cancel,
+		updated: make(chan struct{}),
+	}
+
+	p.mu.Lock()
+	if p.current != nil {
+		// the old stream keeps recording while it's still being played,
+		// such as while it fades out
+		p.current.replaced = true
+		p.releaseIfUnused(p.current)
+	}
+	p.generation++
+	generation := p.generation
+	p.captures[generation] = c
+	p.current = c
+	p.behind = 0
+	p.pausedAt = time.Time{}
+	p.mu.Unlock()
+
+	go p.record(ctx, c, streamURL)
+
+	return fmt.Sprintf("http://%s/%d", p.listener.Addr(), generation), nil
+}
+
+// newStorage creates a buffer in memory, or in a file in p.dir when set
+func (p *Proxy) newStorage() (storage, error) {
+	if p.dir == "" {
+		return make(memory, p.size), nil
+	}
+
+	file, err := newTempFile(p.dir)
+	if err != nil {
+		err = fmt.Errorf("failed to create timeshift buffer file: %w", err)
+		return nil, err
+	}
+	return file, nil
+}
+
+// record copies streamURL into c until ctx is canceled or the stream ends
+func (p *Proxy) record(ctx context.Context, c *capture, streamURL string) {
+	err := p.copyStream(ctx, c, streamURL)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c.stopped = true
+	c.err = err
+	p.notify(c)
+}
+
+func (p *Proxy) copyStream(ctx context.Context, c *capture, streamURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build stream request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to open stream: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to open stream: %s", resp.Status)
+	}
+
+	p.mu.Lock()
+	c.contentType = resp.Header.Get("Content-Type")
+	if p.Tap != nil && c == p.current {
+		p.Tap.Start(c.contentType)
+	}
+	p.mu.Unlock()
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			p.mu.Lock()
+			if c.released {
+				p.mu.Unlock()
+				return nil
+			}
+			writeErr := c.ring.write(buf[:n], time.Now())
+			p.notify(c)
+			if p.Tap != nil && c == p.current {
+				p.Tap.Write(buf[:n])
+			}
+			p.mu.Unlock()
+			if writeErr != nil {
+				return fmt.Errorf("failed to write timeshift buffer: %w", writeErr)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read stream: %w", err)
+		}
+	}
+}
+
+// notify wakes up players waiting on c. p.mu must be held.
+func (p *Proxy) notify(c *capture) {
+	close(c.updated)
+	c.updated = make(chan struct{})
+}
+
+// releaseIfUnused stops recording a replaced capture nobody is reading and
+// frees it, keeping its storage as the spare if there isn't one. p.mu must
+// be held.
+func (p *Proxy) releaseIfUnused(c *capture) {
+	if !c.replaced || c.readers > 0 || c.released {
+		return
+	}
+
+	c.cancel()
+	c.released = true
+	if p.spare == nil {
+		p.spare = c.ring.store
+	} else {
+		c.ring.store.Close()
+	}
+	for generation, other := range p.captures {
+		if other == c {
+			delete(p.captures, generation)
+		}
+	}
+}
+
+// ServeHTTP streams a capture to the player starting from the current
+// playback position
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	generation, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	p.mu.Lock()
+	c, ok := p.captures[generation]
+	if !ok {
+		p.mu.Unlock()
+		http.NotFound(w, r)
+		return
+	}
+	c.readers++
+	offset := c.ring.written
+	if c == p.current && p.behind > 0 {
+		offset = c.ring.offsetAt(time.Now().Add(-p.clampedBehind(time.Now())))
+	}
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		c.readers--
+		p.releaseIfUnused(c)
+		p.mu.Unlock()
+	}()
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	buf := make([]byte, chunkSize)
+	for {
+		p.mu.Lock()
+		n, err := c.ring.readAt(buf, offset)
+		if err == ErrOverwritten {
+			// the player fell further behind than the buffer holds
+			offset = c.ring.oldest()
+			p.mu.Unlock()
+			continue
+		}
+		updated, stopped, captureErr, contentType := c.updated, c.stopped, c.err, c.contentType
+		p.mu.Unlock()
+		if err != nil {
+			return
+		}
+
+		if n > 0 {
+			if !started {
+				started = true
+				if contentType != "" {
+					w.Header().Set("Content-Type", contentType)
+				}
+				w.WriteHeader(http.StatusOK)
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			offset += int64(n)
+			continue
+		}
+
+		if stopped {
+			if !started && captureErr != nil {
+				http.Error(w, captureErr.Error(), http.StatusBadGateway)
+			}
+			return
+		}
+
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Behind is how far playback is behind the live stream. It keeps growing
+// while paused. A nil Proxy is always live.
+func (p *Proxy) Behind() time.Duration {
+	if p == nil {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.clampedBehind(time.Now())
+}
+
+// clampedBehind is how far behind playback is at now, no further back than
+// what's buffered. p.mu must be held.
+func (p *Proxy) clampedBehind(now time.Time) time.Duration {
+	behind := p.behind
+	if !p.pausedAt.IsZero() {
+		behind += now.Sub(p.pausedAt)
+	}
+	if p.current != nil {
+		if span := p.current.ring.span(now); behind > span {
+			behind = span
+		}
+	}
+	if behind < 0 {
+		behind = 0
+	}
+	return behind
+}
+
+// Paused reports whether playback is paused
+func (p *Proxy) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return !p.pausedAt.IsZero()
+}
+
+// Pause marks playback as paused. The stream keeps being captured, so the
+// player should be stopped and reloaded when resuming.
+func (p *Proxy) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pausedAt.IsZero() {
+		p.pausedAt = time.Now()
+	}
+}
+
+// Resume continues playback from where it was paused
+func (p *Proxy) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.behind = p.clampedBehind(time.Now())
+	p.pausedAt = time.Time{}
+}
+
+// Rewind moves playback back by d, or forward when d is negative, and
+// resumes it if paused
+func (p *Proxy) Rewind(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.behind = p.clampedBehind(time.Now()) + d
+	p.pausedAt = time.Time{}
+	p.behind = p.clampedBehind(time.Now())
+}
+
+// Live jumps playback to the live stream
+func (p *Proxy) Live() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.behind = 0
+	p.pausedAt = time.Time{}
+}
+
+// Close stops capturing and serving streams and frees their buffers
+func (p *Proxy) Close() error {
+	err := p.server.Close()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.captures {
+		c.cancel()
+		c.replaced = true
+		c.readers = 0
+		p.releaseIfUnused(c)
+	}
+	p.current = nil
+	if p.spare != nil {
+		p.spare.Close()
+		p.spare = nil
+	}
+
+	return err
+}
@@ -0,0 +1,214 @@
+package timeshift
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingTap keeps what a Proxy taps
+type recordingTap struct {
+	mu           sync.Mutex
+	contentTypes []string
+	audio        bytes.Buffer
+}
+
+func (t *recordingTap) Start(contentType string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.contentTypes = append(t.contentTypes, contentType)
+}
+
+func (t *recordingTap) Write(audio []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.audio.Write(audio)
+}
+
+func (t *recordingTap) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.audio.String()
+}
+
+func TestProxyTap(t *testing.T) {
+	station := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/aac")
+		w.Write([]byte("live audio"))
+	}))
+	defer station.Close()
+
+	proxy, err := New(PassThroughSize, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	tap := &recordingTap{}
+	proxy.Tap = tap
+
+	// the tap is fed without anything playing from the proxy
+	if _, err := proxy.Capture(station.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tap.String() != "live audio" {
+		if time.Now().After(deadline) {
+			t.Fatalf("tapped %q, want live audio", tap.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	tap.mu.Lock()
+	defer tap.mu.Unlock()
+	if len(tap.contentTypes) != 1 || tap.contentTypes[0] != "audio/aac" {
+		t.Errorf("started %v, want audio/aac", tap.contentTypes)
+	}
+}
+
+func TestProxyTapSkipsReplacedStreams(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	old := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.(http.Flusher).Flush()
+		close(started)
+		<-release
+		w.Write([]byte("old audio"))
+	}))
+	defer old.Close()
+	defer close(release)
+	current := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/aac")
+		w.Write([]byte("new audio"))
+	}))
+	defer current.Close()
+
+	proxy, err := New(PassThroughSize, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	tap := &recordingTap{}
+	proxy.Tap = tap
+
+	if _, err := proxy.Capture(old.URL); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if _, err := proxy.Capture(current.URL); err != nil {
+		t.Fatal(err)
+	}
+	release <- struct{}{}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for tap.String() != "new audio" {
+		if time.Now().After(deadline) {
+			t.Fatalf("tapped %q, want only new audio", tap.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProxyReusesReleasedBuffers(t *testing.T) {
+	station := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("audio"))
+	}))
+	defer station.Close()
+
+	proxy, err := New(PassThroughSize, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	stores := map[*byte]bool{}
+	for i := 0; i < 4; i++ {
+		if _, err := proxy.Capture(station.URL); err != nil {
+			t.Fatal(err)
+		}
+		proxy.mu.Lock()
+		stores[&proxy.current.ring.store.(memory)[0]] = true
+		proxy.mu.Unlock()
+	}
+
+	// nothing was playing, so each replaced buffer is free for the next
+	if len(stores) != 2 {
+		t.Errorf("used %d buffers for 4 streams, want 2", len(stores))
+	}
+}
+
+func TestProxyKeepsRecordingReplacedStreamsBeingPlayed(t *testing.T) {
+	chunks := make(chan string)
+	old := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for {
+			select {
+			case chunk := <-chunks:
+				w.Write([]byte(chunk))
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer old.Close()
+	current := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("new audio"))
+	}))
+	defer current.Close()
+
+	proxy, err := New(PassThroughSize, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+
+	oldURL, err := proxy.Capture(old.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the player starts reading once the stream sends something
+	go func() { chunks <- "fading " }()
+	resp, err := http.Get(oldURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	read := func(want string) {
+		t.Helper()
+		got := make([]byte, len(want))
+		if _, err := io.ReadFull(resp.Body, got); err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("read %q, want %q", got, want)
+		}
+	}
+	read("fading ")
+
+	if _, err := proxy.Capture(current.URL); err != nil {
+		t.Fatal(err)
+	}
+	chunks <- "out"
+	read("out")
+
+	// letting go of the old stream stops recording it
+	resp.Body.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		proxy.mu.Lock()
+		captures := len(proxy.captures)
+		proxy.mu.Unlock()
+		if captures == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("replaced capture was never released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
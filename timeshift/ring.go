@@ -0,0 +1,160 @@
+package timeshift
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// ErrOverwritten is returned when reading audio which has already been
+// overwritten by newer audio
+var ErrOverwritten = errors.New("audio is no longer buffered")
+
+// storage is where a ring keeps its bytes
+type storage interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+}
+
+// memory is storage held in memory
+type memory []byte
+
+func (m memory) ReadAt(p []byte, off int64) (int, error) {
+	return copy(p, m[off:]), nil
+}
+
+func (m memory) WriteAt(p []byte, off int64) (int, error) {
+	return copy(m[off:], p), nil
+}
+
+func (m memory) Close() error {
+	return nil
+}
+
+// tempFile is storage in a file which is removed when closed
+type tempFile struct {
+	*os.File
+}
+
+func newTempFile(dir string) (*tempFile, error) {
+	file, err := ioutil.TempFile(dir, "timeshift-*.buf")
+	if err != nil {
+		return nil, err
+	}
+	return &tempFile{File: file}, nil
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// mark records when the byte at offset was captured
+type mark struct {
+	offset int64
+	at     time.Time
+}
+
+// ring is a bounded buffer of captured audio. Offsets count every byte ever
+// written, so they keep increasing while only the last size bytes can be
+// read.
+type ring struct {
+	store   storage
+	size    int64
+	written int64
+	marks   []mark
+}
+
+func newRing(store storage, size int64) *ring {
+	return &ring{store: store, size: size}
+}
+
+// oldest is the offset of the oldest byte still buffered
+func (r *ring) oldest() int64 {
+	if r.written < r.size {
+		return 0
+	}
+	return r.written - r.size
+}
+
+// write appends p, captured at, overwriting the oldest audio when full
+func (r *ring) write(p []byte, at time.Time) error {
+	r.marks = append(r.marks, mark{offset: r.written, at: at})
+	for len(p) > 0 {
+		pos := r.written % r.size
+		n := int64(len(p))
+		if n > r.size-pos {
+			n = r.size - pos
+		}
+		if _, err := r.store.WriteAt(p[:n], pos); err != nil {
+			return err
+		}
+		r.written += n
+		p = p[n:]
+	}
+
+	// keep the mark covering the oldest byte so its time is still known
+	oldest := r.oldest()
+	drop := 0
+	for drop+1 < len(r.marks) && r.marks[drop+1].offset <= oldest {
+		drop++
+	}
+	r.marks = r.marks[drop:]
+
+	return nil
+}
+
+// readAt reads buffered audio starting at offset into p
+func (r *ring) readAt(p []byte, offset int64) (int, error) {
+	if offset < r.oldest() {
+		return 0, ErrOverwritten
+	}
+	if available := r.written - offset; int64(len(p)) > available {
+		p = p[:available]
+	}
+
+	read := 0
+	for read < len(p) {
+		pos := (offset + int64(read)) % r.size
+		end := len(p)
+		if int64(end-read) > r.size-pos {
+			end = read + int(r.size-pos)
+		}
+		n, err := r.store.ReadAt(p[read:end], pos)
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+
+	return read, nil
+}
+
+// offsetAt is the offset of the audio captured at t, clamped to what's
+// buffered
+func (r *ring) offsetAt(t time.Time) int64 {
+	offset := r.oldest()
+	for _, m := range r.marks {
+		if m.at.After(t) {
+			break
+		}
+		if m.offset > offset {
+			offset = m.offset
+		}
+	}
+	return offset
+}
+
+// span is how much time is buffered, as of now
+func (r *ring) span(now time.Time) time.Duration {
+	if len(r.marks) == 0 {
+		return 0
+	}
+	return now.Sub(r.marks[0].at)
+}
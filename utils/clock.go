@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// clockSkews holds each host's clock minus ours, learned from the Date
+// header of responses
+var clockSkews = struct {
+	sync.Mutex
+	byHost map[string]time.Duration
+}{byHost: map[string]time.Duration{}}
+
+// recordClockSkew compares the server's Date header to the local clock at the
+// midpoint of the request, smoothing it with earlier measurements since Date
+// only has second precision
+func recordClockSkew(host string, header http.Header, sentAt, receivedAt time.Time) {
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	midpoint := sentAt.Add(receivedAt.Sub(sentAt) / 2)
+	skew := date.Sub(midpoint)
+
+	clockSkews.Lock()
+	defer clockSkews.Unlock()
+	if previous, ok := clockSkews.byHost[host]; ok {
+		skew = previous + (skew-previous)/4
+	}
+	clockSkews.byHost[host] = skew
+}
+
+// ClockSkew is how far ahead of the local clock the server for rawURL is, as
+// seen from earlier requests to it. ok is false if it hasn't been measured.
+func ClockSkew(rawURL string) (skew time.Duration, ok bool) {
+	uri, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, false
+	}
+
+	clockSkews.Lock()
+	defer clockSkews.Unlock()
+	skew, ok = clockSkews.byHost[uri.Host]
+	return skew, ok
+}
@@ -2,38 +2,380 @@ package utils
 
 import (
 	"bytes"
+	"container/list"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 )
 
+const (
+	// UserAgent identifies the player to the stations' servers
+	UserAgent = "stream-player (+https://github.com/codegoalie/stream-player)"
+
+	defaultRetries      = 2
+	defaultBackoff      = 500 * time.Millisecond
+	defaultMaxBodyBytes = 5 << 20
+	defaultHostInterval = 250 * time.Millisecond
+	defaultCacheEntries = 64
+)
+
+// ErrBodyTooLarge is returned for responses larger than a Client's
+// MaxBodyBytes
+var ErrBodyTooLarge = errors.New("response body is too large")
+
+// ErrHostBusy is returned when a host asked to wait past the request's
+// deadline. It isn't retried, since trying again only waits longer.
+var ErrHostBusy = errors.New("host asked to wait past the deadline")
+
+// StatusError is returned for responses other than 200 OK, so error pages
+// aren't handed to parsers
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// RetryAfter is how long the server asked to wait, if it did
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s responded %s", e.URL, e.Status)
+}
+
+// Temporary reports whether the request may succeed if tried again
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode >= 500
+}
+
 // DefaultHTTPClient defines a nicely configured HTTP client
 var DefaultHTTPClient = &http.Client{
 	Timeout: time.Second * 30,
 	Transport: &http.Transport{
-		Dial: (&net.Dialer{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
 			Timeout: time.Second * 10,
-		}).Dial,
+		}).DialContext,
 		TLSHandshakeTimeout: time.Second * 10,
 	},
 }
 
-// HTTPGet issues an HTTP GET request to URL and returns the body in a buffer
+// DefaultClient is the Client used to fetch track info
+var DefaultClient = NewClient(DefaultHTTPClient)
+
+// Client makes GET requests politely: it checks status codes, retries
+// failures with backoff, revalidates with ETag and Last-Modified, spaces out
+// requests to each host and honors Retry-After
+type Client struct {
+	HTTP *http.Client
+	// Retries is how many times a failed request is tried again
+	Retries int
+	// Backoff is how long to wait before the first retry, doubling after
+	// each one
+	Backoff time.Duration
+	// MaxBodyBytes limits the size of bodies read by Get
+	MaxBodyBytes int64
+	// HostInterval is the least time between requests to the same host
+	HostInterval time.Duration
+	// CacheEntries is how many revalidatable bodies are kept, dropping the
+	// least recently used
+	CacheEntries int
+	// Observe, if set, is told how long each Get to host took, including
+	// reading the body
+	Observe func(host string, d time.Duration)
+
+	mu         sync.Mutex
+	nextByHost map[string]time.Time
+	cache      map[string]*list.Element
+	// cacheOrder holds cachedResponses, most recently used first
+	cacheOrder *list.List
+}
+
+// cachedResponse is a body along with the validators to check it's still
+// current
+type cachedResponse struct {
+	key          string
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// NewClient creates a Client making requests with httpClient
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		HTTP:         httpClient,
+		Retries:      defaultRetries,
+		Backoff:      defaultBackoff,
+		MaxBodyBytes: defaultMaxBodyBytes,
+		HostInterval: defaultHostInterval,
+		CacheEntries: defaultCacheEntries,
+		nextByHost:   map[string]time.Time{},
+		cache:        map[string]*list.Element{},
+		cacheOrder:   list.New(),
+	}
+}
+
+// HTTPGet issues an HTTP GET request to URL with DefaultClient and returns
+// the body in a buffer
 func HTTPGet(url string) (*bytes.Buffer, error) {
-	resp, err := DefaultHTTPClient.Get(url)
+	return DefaultClient.Get(context.Background(), url)
+}
+
+// Get fetches rawURL, which is canceled along with ctx, and returns the body
+// in a buffer. A body which hasn't changed since it was last fetched is
+// returned from memory.
+func (c *Client) Get(ctx context.Context, rawURL string) (*bytes.Buffer, error) {
+	key := cacheKey(rawURL)
+	cached, hasCached := c.cached(key)
+
+	if c.Observe != nil {
+		startedAt := time.Now()
+		defer func() {
+			host := ""
+			if u, err := url.Parse(rawURL); err == nil {
+				host = u.Host
+			}
+			c.Observe(host, time.Since(startedAt))
+		}()
+	}
+
+	resp, err := c.do(ctx, rawURL, cached, hasCached)
 	if err != nil {
-		err = fmt.Errorf("failed to issue HttpGet: %w", err)
 		return nil, err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return bytes.NewBuffer(append([]byte(nil), cached.body...)), nil
+	}
 
 	buf := new(bytes.Buffer)
-	_, err = buf.ReadFrom(resp.Body)
-	_ = resp.Body.Close()
+	var body io.Reader = resp.Body
+	if c.MaxBodyBytes > 0 {
+		body = io.LimitReader(resp.Body, c.MaxBodyBytes+1)
+	}
+	_, err = buf.ReadFrom(body)
 	if err != nil {
 		err = fmt.Errorf("failed to read HttpGet body: %w", err)
 		return nil, err
 	}
+	if c.MaxBodyBytes > 0 && int64(buf.Len()) > c.MaxBodyBytes {
+		return nil, fmt.Errorf("failed to read HttpGet body: %w", ErrBodyTooLarge)
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag != "" || lastModified != "" {
+		c.store(cachedResponse{key: key, etag: etag, lastModified: lastModified, body: append([]byte(nil), buf.Bytes()...)})
+	}
 
 	return buf, nil
 }
+
+// cached finds the response cached for key, marking it recently used
+func (c *Client) cached(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.cache[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	c.cacheOrder.MoveToFront(element)
+	return element.Value.(cachedResponse), true
+}
+
+// store caches resp, dropping the least recently used responses beyond
+// CacheEntries
+func (c *Client) store(resp cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.cache[resp.key]; ok {
+		element.Value = resp
+		c.cacheOrder.MoveToFront(element)
+	} else {
+		c.cache[resp.key] = c.cacheOrder.PushFront(resp)
+	}
+
+	for c.CacheEntries >= 0 && c.cacheOrder.Len() > c.CacheEntries {
+		oldest := c.cacheOrder.Back()
+		c.cacheOrder.Remove(oldest)
+		delete(c.cache, oldest.Value.(cachedResponse).key)
+	}
+}
+
+// Open fetches rawURL, which is canceled along with ctx, and returns the
+// response for the caller to read and close. The status is always 200 OK.
+func (c *Client) Open(ctx context.Context, rawURL string) (*http.Response, error) {
+	return c.do(ctx, rawURL, cachedResponse{}, false)
+}
+
+// do makes the request, retrying temporary failures. With a cached response
+// the request is conditional and may return 304 Not Modified.
+func (c *Client) do(ctx context.Context, rawURL string, cached cachedResponse, conditional bool) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			delay := c.Backoff << uint(attempt-1)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		resp, err := c.once(ctx, rawURL, cached, conditional)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.Retries || !retryable(ctx, err) {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) once(ctx context.Context, rawURL string, cached cachedResponse, conditional bool) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		err = fmt.Errorf("failed to build HttpGet request: %w", err)
+		return nil, err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	if conditional {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	if err := c.wait(ctx, req.URL.Host); err != nil {
+		return nil, err
+	}
+
+	sentAt := time.Now()
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		err = fmt.Errorf("failed to issue HttpGet: %w", err)
+		return nil, err
+	}
+	recordClockSkew(req.URL.Host, resp.Header, sentAt, time.Now())
+
+	if resp.StatusCode == http.StatusOK || (conditional && resp.StatusCode == http.StatusNotModified) {
+		return resp, nil
+	}
+
+	// drain a little so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	_ = resp.Body.Close()
+
+	statusErr := &StatusError{
+		URL:        rawURL,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: retryAfter(resp.Header, time.Now()),
+	}
+	if statusErr.RetryAfter > 0 {
+		c.delayHost(req.URL.Host, time.Now().Add(statusErr.RetryAfter))
+	}
+	return nil, statusErr
+}
+
+// wait blocks until a request to host is allowed. The slot it reserves is
+// given back if the request isn't made.
+func (c *Client) wait(ctx context.Context, host string) error {
+	c.mu.Lock()
+	now := time.Now()
+	previous := c.nextByHost[host]
+	at := previous
+	if at.Before(now) {
+		at = now
+	}
+	reserved := at.Add(c.HostInterval)
+	c.nextByHost[host] = reserved
+	c.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(at) {
+		c.release(host, reserved, previous)
+		return fmt.Errorf("failed to issue HttpGet: %s asked to wait %s: %w", host, delay.Round(time.Second), ErrHostBusy)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		c.release(host, reserved, previous)
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// release gives back a slot reserved by wait, unless a later request or a
+// Retry-After has moved host's next slot since
+func (c *Client) release(host string, reserved, previous time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nextByHost[host].Equal(reserved) {
+		c.nextByHost[host] = previous
+	}
+}
+
+// delayHost holds off requests to host until at
+func (c *Client) delayHost(host string, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nextByHost[host].Before(at) {
+		c.nextByHost[host] = at
+	}
+}
+
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrHostBusy) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	return true
+}
+
+// retryAfter reads a Retry-After header given in seconds or as a date
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// cacheKey identifies rawURL without the "_" query parameter stations add
+// to bust caches
+func cacheKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Del("_")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	conditional := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + r.URL.Path + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			conditional[r.URL.Path]++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	client.HostInterval = 0
+	client.CacheEntries = 2
+	get := func(path string) {
+		t.Helper()
+		body, err := client.Get(context.Background(), server.URL+path+"?_="+fmt.Sprint(time.Now().UnixNano()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body.String() != path {
+			t.Errorf("%s returned %q", path, body.String())
+		}
+	}
+
+	get("/a")
+	get("/b")
+	get("/a")
+	// /b is the least recently used, so /c pushes it out
+	get("/c")
+	get("/a")
+	get("/b")
+
+	if conditional["/a"] != 2 {
+		t.Errorf("/a was revalidated %d times, want 2", conditional["/a"])
+	}
+	if conditional["/b"] != 0 {
+		t.Errorf("/b was revalidated %d times after being evicted", conditional["/b"])
+	}
+	if len(client.cache) != 2 || client.cacheOrder.Len() != 2 {
+		t.Errorf("cache holds %d responses, want 2", len(client.cache))
+	}
+}
+
+func TestClientGiveUpAtDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	client.HostInterval = 0
+	client.Backoff = time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	startedAt := time.Now()
+	if _, err := client.Get(ctx, server.URL); err == nil {
+		t.Fatal("a failing server succeeded")
+	}
+	if took := time.Since(startedAt); took > 500*time.Millisecond {
+		t.Errorf("retries took %v, past the deadline", took)
+	}
+}
+
+func TestClientObserve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	var hosts []string
+	client.Observe = func(host string, d time.Duration) {
+		hosts = append(hosts, host)
+	}
+	if _, err := client.Get(context.Background(), server.URL+"/info"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.TrimPrefix(server.URL, "http://")
+	if len(hosts) != 1 || hosts[0] != want {
+		t.Errorf("observed %v, want [%s]", hosts, want)
+	}
+}
+
+func TestClientHostBusyPastDeadline(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client())
+	client.Backoff = time.Millisecond
+	host := strings.TrimPrefix(server.URL, "http://")
+	busyUntil := time.Now().Add(time.Hour)
+	client.delayHost(host, busyUntil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := client.Get(ctx, server.URL); !errors.Is(err, ErrHostBusy) {
+		t.Fatalf("got error %v, want ErrHostBusy", err)
+	}
+	if requests != 0 {
+		t.Errorf("made %d requests to a busy host", requests)
+	}
+	// the failed attempt doesn't push the host's next slot back
+	if next := client.nextByHost[host]; !next.Equal(busyUntil) {
+		t.Errorf("next request to the host is at %v, want %v", next, busyUntil)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxVolume is the loudest volume VLC is asked to play, as a percent
+const maxVolume = 200
+
+// stream is a station's audio for playAudio to play
+type stream struct {
+	url string
+	// gain in decibels evens out stations mastered at different levels
+	gain float64
+	// seek reloads the same station at a new position, so it is never
+	// crossfaded
+	seek bool
+}
+
+// scaledVolume applies gain in decibels to a volume percentage
+func scaledVolume(volume int, gain float64) int {
+	scaled := int(math.Round(float64(volume) * math.Pow(10, gain/20)))
+	if scaled < 0 {
+		return 0
+	}
+	if scaled > maxVolume {
+		return maxVolume
+	}
+	return scaled
+}
+
+// gainFlags collects -gain values of the form station=decibels, where
+// station is any prefix of a station's name. Stations are looked up by
+// byStation once every station has been added.
+type gainFlags map[string]float64
+
+func (f gainFlags) String() string {
+	parts := []string{}
+	for prefix, gain := range f {
+		parts = append(parts, fmt.Sprintf("%s=%g", prefix, gain))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func (f gainFlags) Set(value string) error {
+	split := strings.SplitN(value, "=", 2)
+	if len(split) != 2 || strings.TrimSpace(split[0]) == "" {
+		return fmt.Errorf("expected station=decibels, got %q", value)
+	}
+
+	gain, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(split[1]), "dB"), 64)
+	if err != nil {
+		return fmt.Errorf("invalid gain %q: %w", split[1], err)
+	}
+
+	f[strings.TrimSpace(split[0])] = gain
+	return nil
+}
+
+// byStation finds the station each gain is for, keyed by its name
+func (f gainFlags) byStation() (map[string]float64, error) {
+	gains := map[string]float64{}
+	for prefix, gain := range f {
+		index, err := stationIndex(prefix)
+		if err != nil {
+			return nil, err
+		}
+		gains[medias[index].Name()] = gain
+	}
+	return gains, nil
+}
+
+// stationIndex finds the station whose name starts with prefix, ignoring case
+func stationIndex(prefix string) (int, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	for i, media := range medias {
+		if prefix != "" && strings.HasPrefix(strings.ToLower(media.Name()), prefix) {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("unknown station %q", prefix)
+}
@@ -1,10 +1,7 @@
 package wdwnt
 
 import (
-	"encoding/json"
-	"fmt"
-	"time"
-
+	"github.com/codegoalie/stream-player/live365"
 	"github.com/codegoalie/stream-player/models"
 )
 
@@ -12,6 +9,10 @@ const tunesName = "WDWNTunes"
 const tunesStreamURL = "https://streaming.live365.com/a31769"
 const tunesInfoURL = "https://api.live365.com/station/a31769"
 
+const tunesDescription = "Disney park music and more from WDW News Today"
+const tunesHomepage = "https://live365.com/station/WDWNTunes-a31769"
+const tunesGenre = "Disney"
+
 type Tunes struct{}
 
 // Name is the userpresentable name of the stream
@@ -31,34 +32,22 @@ func (t Tunes) InfoURL() string {
 
 // ParseTrackInfo parses the provided bytes into a TrackInfo
 func (t Tunes) ParseTrackInfo(raw []byte) (*models.TrackInfo, error) {
-	resp := &wdwnTunesResponse{}
-	err := json.Unmarshal(raw, &resp)
-	if err != nil {
-		err = fmt.Errorf("failed to unmarshal WDWNTunes info: %w", err)
-		return nil, err
-	}
-	info := &models.TrackInfo{}
+	return live365.ParseTrackInfo(raw)
+}
 
-	startedAt, err := time.Parse("2006-01-02 15:04:05-07:00", resp.CurrentTrack.Start)
-	if err != nil {
-		err = fmt.Errorf("failed to parse WDWNTunes started at info: %w", err)
-		startedAt = time.Time{}
-	}
+// ParseHistory parses the provided bytes into the recently played tracks
+func (t Tunes) ParseHistory(raw []byte) ([]*models.TrackInfo, error) {
+	return live365.ParseHistory(raw)
+}
 
-	info.Title = resp.CurrentTrack.Title
-	info.Artist = resp.CurrentTrack.Artist
-	info.Album = ""
-	info.Duration = resp.CurrentTrack.Duration
-	info.StartedAt = startedAt
+func (t Tunes) Description() string {
+	return tunesDescription
+}
 
-	return info, nil
+func (t Tunes) Homepage() string {
+	return tunesHomepage
 }
 
-type wdwnTunesResponse struct {
-	CurrentTrack struct {
-		Title    string  `json:"title"`
-		Artist   string  `json:"artist"`
-		Start    string  `json:"start"`
-		Duration float64 `json:"duration"`
-	} `json:"current-track"`
+func (t Tunes) Genre() string {
+	return tunesGenre
 }
@@ -0,0 +1,27 @@
+package wdwnt
+
+import (
+	"testing"
+
+	"github.com/codegoalie/stream-player/sourcetest"
+)
+
+// Payloads are parsed by the live365 package, which is tested there
+func TestTunes(t *testing.T) {
+	sourcetest.Run(t, Tunes{}, sourcetest.Options{})
+}
+
+// A null payload used to panic by unmarshaling into a nil response
+func TestTunesNull(t *testing.T) {
+	info, err := Tunes{}.ParseTrackInfo([]byte("null"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "" {
+		t.Errorf("title is %q, want empty", info.Title)
+	}
+
+	if _, err := (Tunes{}).ParseHistory([]byte("null")); err != nil {
+		t.Fatal(err)
+	}
+}
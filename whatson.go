@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/codegoalie/stream-player/models"
+	"github.com/gosuri/uilive"
+)
+
+// nowPlaying is a snapshot of what a single station is playing
+type nowPlaying struct {
+	Station   string  `json:"station"`
+	Title     string  `json:"title"`
+	Artist    string  `json:"artist"`
+	Album     string  `json:"album,omitempty"`
+	Genre     string  `json:"genre,omitempty"`
+	Homepage  string  `json:"homepage,omitempty"`
+	Remaining float64 `json:"remaining_seconds,omitempty"`
+	Error     string  `json:"error,omitempty"`
+
+	info *models.TrackInfo
+}
+
+func runWhatsOn(args []string) {
+	fs := flag.NewFlagSet("whats-on", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	watch := fs.Duration("watch", 0, "refresh every interval instead of printing once (e.g. 10s)")
+	workers := fs.Int("workers", 4, "number of stations to fetch at once")
+	timeout := fs.Duration("timeout", 5*time.Second, "timeout for each station's metadata request")
+	_ = fs.Parse(args)
+
+	if *watch <= 0 {
+		results := fetchAllNowPlaying(medias, *workers, *timeout)
+		if err := printNowPlaying(os.Stdout, results, *asJSON); err != nil {
+			log.Fatal("failed to print now playing", err)
+		}
+		return
+	}
+
+	var out io.Writer = os.Stdout
+	if !*asJSON {
+		writer := uilive.New()
+		writer.Start()
+		defer writer.Stop()
+		out = writer
+	}
+
+	for {
+		results := fetchAllNowPlaying(medias, *workers, *timeout)
+		if err := printNowPlaying(out, results, *asJSON); err != nil {
+			log.Fatal("failed to print now playing", err)
+		}
+		time.Sleep(*watch)
+	}
+}
+
+// fetchAllNowPlaying fetches the current track of every source using at most
+// workers concurrent requests. Results are in the same order as sources.
+func fetchAllNowPlaying(sources []models.MediaSource, workers int, timeout time.Duration) []nowPlaying {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]nowPlaying, len(sources))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = fetchNowPlaying(sources[i], timeout)
+			}
+		}()
+	}
+
+	for i := range sources {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+func fetchNowPlaying(fetcher models.InfoFetcher, timeout time.Duration) nowPlaying {
+	result := nowPlaying{Station: fetcher.Name()}
+	if describer, ok := fetcher.(models.Describer); ok {
+		result.Genre = describer.Genre()
+		result.Homepage = describer.Homepage()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	info, err := fetchTrackInfo(ctx, fetcher)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.info = info
+	result.Title = info.Title
+	result.Artist = info.Artist
+	result.Album = info.Album
+	if info.Duration > 0 && !info.StartedAt.IsZero() {
+		endsAt := info.StartedAt.Add(time.Second * time.Duration(info.Duration))
+		if left := time.Until(endsAt); left > 0 {
+			result.Remaining = math.Floor(left.Seconds())
+		}
+	}
+
+	return result
+}
+
+func printNowPlaying(w io.Writer, results []nowPlaying, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(results)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATION\tTITLE\tARTIST\tREMAINING")
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(tw, "%s\t(error: %s)\t\t\n", result.Station, result.Error)
+			continue
+		}
+
+		remaining := "-"
+		if result.Remaining > 0 {
+			remaining = fmt.Sprintf("%02.f:%02.f", math.Floor(result.Remaining/60), math.Mod(result.Remaining, 60))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result.Station, result.Title, result.Artist, remaining)
+	}
+
+	return tw.Flush()
+}